@@ -0,0 +1,122 @@
+package action
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/digitalocean/gocop/gocop"
+	"github.com/spf13/cobra"
+)
+
+var good, bad string
+var bisectRuns int
+var failureRate, maxFailureRate, confidence float64
+
+var bisectCmd = &cobra.Command{
+	Use:   "bisect <package>",
+	Short: "finds the commit which broke or made a package flaky with git bisect",
+	Long: `Drives git bisect run between a good and a bad commit, running the tests of the package at each commit
+repeatedly. A commit is bad once the failure rate is above --max-failure-rate with the given confidence, so a
+test which became flaky can be bisected by running it enough times to see it fail. Without --runs, the
+number of runs is chosen to observe at least one failure at --failure-rate with that confidence.
+
+Run it from a clean work tree of the repository; the commit checked out before is restored afterwards.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// a pattern matching several packages would fail to build, and be skipped, at every commit
+		_, _, err := gocop.ResolvePackage(".", args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		runs := bisectRunCount()
+		log.Printf("running %s %d times at each commit", args[0], runs)
+
+		self, err := os.Executable()
+		if err != nil {
+			log.Fatal(err)
+		}
+		step := []string{self, "bisect-step", args[0],
+			"--runs", strconv.Itoa(runs),
+			"--max-failure-rate", strconv.FormatFloat(maxFailureRate, 'g', -1, 64),
+			"--confidence", strconv.FormatFloat(confidence, 'g', -1, 64),
+			"--run", testRun,
+			"--timeout", testTimeout.String(),
+			"--race=" + strconv.FormatBool(race),
+		}
+		for _, tag := range tags {
+			step = append(step, "--tags", tag)
+		}
+
+		sha, err := gocop.Bisect(".", good, bad, step, os.Stderr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(sha)
+	},
+}
+
+// bisectStepCmd tests the checked out commit for git bisect run, reporting the verdict in its exit status
+var bisectStepCmd = &cobra.Command{
+	Use:    "bisect-step <package>",
+	Short:  "tests a commit for gocop bisect",
+	Hidden: true,
+	Args:   cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		oracle := gocop.BisectOracle{
+			Dir:            ".",
+			Package:        args[0],
+			BuildFlags:     buildFlags(),
+			TestFlags:      testFlags(),
+			Runs:           bisectRunCount(),
+			MaxFailureRate: maxFailureRate,
+			Confidence:     confidence,
+			// the binary is also limited by -test.timeout, this catches tests hanging past it
+			Timeout: testTimeout + time.Minute,
+		}
+
+		verdict, err := oracle.Test()
+		if err != nil {
+			// exit statuses above 127 abort git bisect run
+			log.Println(err)
+			os.Exit(128)
+		}
+		log.Println(verdict.Text())
+		os.Exit(verdict.ExitCode())
+	},
+}
+
+// bisectRunCount is how many times the tests run at each commit
+func bisectRunCount() int {
+	if bisectRuns > 0 {
+		return bisectRuns
+	}
+	runs := gocop.RunsToDetect(failureRate, confidence)
+	if runs < 1 {
+		log.Fatal("--failure-rate must be above 0 without --runs")
+	}
+	return runs
+}
+
+func init() {
+	RootCmd.AddCommand(bisectCmd)
+	RootCmd.AddCommand(bisectStepCmd)
+
+	for _, cmd := range []*cobra.Command{bisectCmd, bisectStepCmd} {
+		cmd.Flags().IntVar(&bisectRuns, "runs", 0, "times the tests run at each commit")
+		cmd.Flags().Float64Var(&failureRate, "failure-rate", 1, "failure rate expected at bad commits, choosing the runs when --runs is not set")
+		cmd.Flags().Float64Var(&maxFailureRate, "max-failure-rate", 0, "highest failure rate of a good commit")
+		cmd.Flags().Float64Var(&confidence, "confidence", 0.95, "confidence level of the failure rate estimates")
+		addTestFlags(cmd.Flags())
+	}
+
+	bisectCmd.Flags().StringVar(&good, "good", "", "a commit at which the package passes")
+	bisectCmd.Flags().StringVar(&bad, "bad", "HEAD", "a commit at which the package fails")
+	err := bisectCmd.MarkFlagRequired("good")
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,65 @@
+package action
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/digitalocean/gocop/gocop"
+	"github.com/spf13/cobra"
+)
+
+var correlateWindow time.Duration
+var minRuns int
+var correlateBranch string
+
+var correlateCmd = &cobra.Command{
+	Use:   "correlate",
+	Short: "reports configurations under which packages fail disproportionately often",
+	Long: `Compares the failure rates of each package in stored runs across their configuration, such as -race, -short
+and build tags, and their environment recorded with --metadata, such as the Go version, OS/arch, CI runner and
+GOMAXPROCS. A configuration is reported when a package fails there more often than in its other runs with
+--confidence confidence, such as a package failing 30% under -race and 0% otherwise.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+
+		db := connectDB()
+		defer func() {
+			err = db.Close()
+			if err != nil {
+				log.Fatalln(err)
+			}
+		}()
+
+		outcomes, err := gocop.GetConfigOutcomes(db, gocop.RunFilter{
+			Repo:   repo,
+			Branch: correlateBranch,
+			Since:  time.Now().Add(-correlateWindow),
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		writeReport(os.Stdout, gocop.Correlate(outcomes, minRuns, confidence))
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(correlateCmd)
+	addDBFlags(correlateCmd.Flags())
+	err := correlateCmd.MarkFlagRequired("pass")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	correlateCmd.Flags().StringVarP(&repo, "repo", "g", "", "repository name")
+	err = correlateCmd.MarkFlagRequired("repo")
+	if err != nil {
+		log.Fatal(err)
+	}
+	correlateCmd.Flags().StringVarP(&correlateBranch, "branch", "b", "", "branch name, all branches unless set")
+	correlateCmd.Flags().DurationVar(&correlateWindow, "window", 30*24*time.Hour, "how far back stored runs are compared")
+	correlateCmd.Flags().IntVar(&minRuns, "min-runs", 10, "runs of a package needed both with and without a configuration")
+	correlateCmd.Flags().Float64Var(&confidence, "confidence", 0.95, "confidence level of the failure rate bounds")
+	addOutputFlags(correlateCmd.Flags())
+}
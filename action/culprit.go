@@ -0,0 +1,75 @@
+package action
+
+import (
+	"log"
+	"os"
+
+	"github.com/digitalocean/gocop/gocop"
+	"github.com/spf13/cobra"
+)
+
+var historyLimit int
+var gitLog bool
+
+var culpritCmd = &cobra.Command{
+	Use:   "culprit <package>",
+	Short: "finds the commits in which a failing package broke from stored history",
+	Long: `Walks the stored runs of a branch back from the latest to find the last run a package passed in and
+the first run it failed in, ignoring failures classified as flaky. With --git-log, the commits of that
+range changing the package or its dependencies are listed as likely culprits.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		pkg := args[0]
+
+		db := connectDB()
+		defer func() {
+			err = db.Close()
+			if err != nil {
+				log.Fatalln(err)
+			}
+		}()
+
+		history, err := gocop.GetCommitHistory(db, repo, branch, pkg, historyLimit)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		culprit, err := gocop.FindCulprit(pkg, history)
+		if err != nil {
+			log.Fatalf("%s on %s: %v", pkg, branch, err)
+		}
+
+		if gitLog && culprit.LastPass != nil {
+			paths, err := gocop.DependencyPaths(".", pkg)
+			if err != nil {
+				log.Fatal(err)
+			}
+			culprit.Commits, err = gocop.CommitLog(".", culprit.Range(), paths)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		writeReport(os.Stdout, culprit)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(culpritCmd)
+	addDBFlags(culpritCmd.Flags())
+	err := culpritCmd.MarkFlagRequired("pass")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	culpritCmd.Flags().StringVarP(&repo, "repo", "g", "", "repository name")
+	err = culpritCmd.MarkFlagRequired("repo")
+	if err != nil {
+		log.Fatal(err)
+	}
+	culpritCmd.Flags().StringVarP(&branch, "branch", "b", "master", "branch name")
+	culpritCmd.Flags().IntVar(&historyLimit, "limit", 500, "how many of the latest runs of the branch are searched")
+	culpritCmd.Flags().BoolVar(&gitLog, "git-log", false, "list the commits of the range changing the package or its dependencies, run from the repository")
+	addOutputFlags(culpritCmd.Flags())
+}
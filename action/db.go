@@ -0,0 +1,25 @@
+package action
+
+import (
+	"database/sql"
+
+	"github.com/digitalocean/gocop/gocop"
+	"github.com/spf13/pflag"
+)
+
+var host, port, dbName, user, password, sslMode string
+
+// addDBFlags registers the flags used to connect to the database
+func addDBFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&host, "host", "a", "localhost", "database host")
+	flags.StringVarP(&port, "port", "t", "5432", "database port")
+	flags.StringVarP(&dbName, "database", "x", "postgres", "database name")
+	flags.StringVarP(&sslMode, "ssl", "y", "require", "database ssl mode")
+	flags.StringVarP(&password, "pass", "p", "", "database password")
+	flags.StringVarP(&user, "user", "u", "postgres", "database username")
+}
+
+// connectDB connects to the database selected by the database flags
+func connectDB() *sql.DB {
+	return gocop.ConnectDB(host, port, user, password, dbName, sslMode)
+}
@@ -0,0 +1,43 @@
+package action
+
+import (
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+var testRun string
+var testTimeout time.Duration
+
+// addTestFlags registers the flags selecting how commands compile and run the tests of a package themselves
+func addTestFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&testRun, "run", "", "run only tests matching the regular expression, as go test -run")
+	flags.BoolVar(&race, "race", false, "build the tests with the race detector")
+	flags.StringSliceVar(&tags, "tags", []string{}, "comma-separated build tags")
+	flags.DurationVar(&testTimeout, "timeout", 10*time.Minute, "timeout of a single run of the tests")
+}
+
+// buildFlags are the go test -c flags selected by the test flags
+func buildFlags() []string {
+	flags := make([]string, 0)
+	if race {
+		flags = append(flags, "-race")
+	}
+	if len(tags) > 0 {
+		flags = append(flags, "-tags", strings.Join(tags, ","))
+	}
+	return flags
+}
+
+// testFlags are the test binary flags selected by the test flags
+func testFlags() []string {
+	flags := make([]string, 0)
+	if testRun != "" {
+		flags = append(flags, "-test.run", testRun)
+	}
+	if testTimeout > 0 {
+		flags = append(flags, "-test.timeout", testTimeout.String())
+	}
+	return flags
+}
@@ -1,9 +1,8 @@
 package action
 
 import (
-	"fmt"
 	"log"
-	"strings"
+	"os"
 
 	"github.com/digitalocean/gocop/gocop"
 	"github.com/spf13/cobra"
@@ -16,15 +15,20 @@ var failedCmd = &cobra.Command{
 	Short: "lists failed packages from test run",
 	Long:  ``,
 	Run: func(cmd *cobra.Command, args []string) {
-		pkgs := gocop.ParseFileFailed(src)
-		fmt.Print(strings.Join(pkgs, "\n"))
+		results := gocop.ParseFileResults(src).Outcome(gocop.ResultFail)
+		if o := loadOwnership(); o != nil {
+			results = o.Results(results)
+		}
+		writeReport(os.Stdout, results)
 	},
 }
 
 func init() {
 	RootCmd.AddCommand(failedCmd)
 
-	failedCmd.Flags().StringVarP(&src, "src", "s", "", "source test output file")
+	failedCmd.Flags().StringVarP(&src, "src", "s", "", "source test output file, or - for stdin")
+	addOutputFlags(failedCmd.Flags())
+	addOwnerFlags(failedCmd.Flags())
 	err := failedCmd.MarkFlagRequired("src")
 	if err != nil {
 		log.Fatal(err)
@@ -1,9 +1,8 @@
 package action
 
 import (
-	"fmt"
 	"log"
-	"strings"
+	"os"
 
 	"github.com/digitalocean/gocop/gocop"
 	"github.com/spf13/cobra"
@@ -16,15 +15,20 @@ var flakyCmd = &cobra.Command{
 	Short: "lists packages suspected of having flaky tests",
 	Long:  ``,
 	Run: func(cmd *cobra.Command, args []string) {
-		pkgs := gocop.FlakyFile(retests...)
-		fmt.Print(strings.Join(pkgs, "\n"))
+		flaky := gocop.FlakyFileReport(retests...)
+		if o := loadOwnership(); o != nil {
+			flaky = o.Flaky(flaky)
+		}
+		writeReport(os.Stdout, flaky)
 	},
 }
 
 func init() {
 	RootCmd.AddCommand(flakyCmd)
 
-	flakyCmd.Flags().StringSliceVarP(&retests, "retests", "r", []string{}, "source output for retests")
+	flakyCmd.Flags().StringSliceVarP(&retests, "retests", "r", []string{}, "source output for retests, or - for stdin")
+	addOutputFlags(flakyCmd.Flags())
+	addOwnerFlags(flakyCmd.Flags())
 	err := flakyCmd.MarkFlagRequired("retests")
 	if err != nil {
 		log.Fatal(err)
@@ -0,0 +1,53 @@
+package action
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/digitalocean/gocop/gocop"
+	"github.com/spf13/cobra"
+)
+
+var htmlOut string
+
+var htmlCmd = &cobra.Command{
+	Use:   "html",
+	Short: "generates a static HTML report for a test run and its retries",
+	Long:  ``,
+	Run: func(cmd *cobra.Command, args []string) {
+		attempts := []gocop.PackageResults{gocop.ParseFileResults(src)}
+		for _, retest := range retests {
+			attempts = append(attempts, gocop.ParseFileResults(retest))
+		}
+
+		err := os.MkdirAll(htmlOut, 0755)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		f, err := os.Create(filepath.Join(htmlOut, "index.html"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+
+		err = gocop.WriteHTML(f, gocop.NewHTMLReport(attempts...))
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(htmlCmd)
+
+	htmlCmd.Flags().StringVarP(&src, "src", "s", "", "source test output file, or - for stdin")
+	err := htmlCmd.MarkFlagRequired("src")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	htmlCmd.Flags().StringSliceVarP(&retests, "retests", "r", []string{}, "source output for retries of the run")
+	htmlCmd.Flags().StringVarP(&htmlOut, "out", "o", "report", "directory to write index.html to")
+}
@@ -0,0 +1,159 @@
+package action
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/digitalocean/gocop/gocop"
+	"github.com/spf13/cobra"
+)
+
+var tracker string
+var issueWindow time.Duration
+var stableRuns int
+var dryRun bool
+var issueLabels []string
+var githubURL, githubRepo, githubToken string
+var jiraURL, jiraProject, jiraIssueType, jiraUser, jiraToken, jiraCloseTransition string
+
+var issuesCmd = &cobra.Command{
+	Use:   "issues",
+	Short: "files, updates and closes issues for flaky tests",
+	Long: `Files an issue for each test found flaky in stored runs, comments on it when the test is flaky
+again and closes it once the package passed in enough runs after the test was last flaky.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+
+		envFlag(cmd, "github-token", "GITHUB_TOKEN", &githubToken)
+		envFlag(cmd, "jira-token", "JIRA_API_TOKEN", &jiraToken)
+
+		var t gocop.IssueTracker
+		switch tracker {
+		case gocop.TrackerGitHub:
+			if githubRepo == "" {
+				log.Fatal("--github-repo is required for the github tracker")
+			}
+			t = gocop.GitHubTracker{URL: githubURL, Repo: githubRepo, Token: githubToken, Labels: issueLabels}
+		case gocop.TrackerJira:
+			if jiraURL == "" || jiraProject == "" {
+				log.Fatal("--jira-url and --jira-project are required for the jira tracker")
+			}
+			t = gocop.JiraTracker{
+				URL:             jiraURL,
+				Project:         jiraProject,
+				IssueType:       jiraIssueType,
+				User:            jiraUser,
+				Token:           jiraToken,
+				CloseTransition: jiraCloseTransition,
+				Labels:          issueLabels,
+			}
+		default:
+			log.Fatalf("unknown tracker %q, expected one of %s|%s", tracker, gocop.TrackerGitHub, gocop.TrackerJira)
+		}
+		if dryRun {
+			t = dryRunTracker{name: t.Name()}
+		}
+
+		db := connectDB()
+		defer func() {
+			err = db.Close()
+			if err != nil {
+				log.Fatalln(err)
+			}
+		}()
+
+		now := time.Now()
+		occurrences, err := gocop.GetFlakyOccurrences(db, repo, now.Add(-issueWindow))
+		if err != nil {
+			log.Fatal(err)
+		}
+		tracked, err := gocop.GetIssues(db, repo, t.Name())
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		sync := gocop.IssueSync{
+			Tracker:    t,
+			Repo:       repo,
+			StableRuns: stableRuns,
+			Passes: func(pkg string, since time.Time) (int, error) {
+				return gocop.CountPassesSince(db, repo, pkg, since)
+			},
+			Now: now,
+		}
+		changed, syncErr := sync.Sync(occurrences, tracked)
+
+		if !dryRun {
+			for _, issue := range changed {
+				err = gocop.SaveIssue(db, issue)
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
+		}
+		if syncErr != nil {
+			log.Fatal(syncErr)
+		}
+	},
+}
+
+// dryRunTracker prints what would be done to issues instead of calling a tracker
+type dryRunTracker struct {
+	name string
+}
+
+func (d dryRunTracker) Name() string {
+	return d.name
+}
+
+func (d dryRunTracker) Open(repo string, o gocop.FlakyOccurrence) (string, string, error) {
+	if o.Test == "" {
+		fmt.Fprintf(os.Stdout, "open %s: flaky in %d runs\n", o.Package, o.Runs)
+	} else {
+		fmt.Fprintf(os.Stdout, "open %s %s: flaky in %d runs\n", o.Package, o.Test, o.Runs)
+	}
+	return "", "", nil
+}
+
+func (d dryRunTracker) Comment(key, body string) error {
+	fmt.Fprintf(os.Stdout, "comment %s\n", key)
+	return nil
+}
+
+func (d dryRunTracker) Close(key, comment string) error {
+	fmt.Fprintf(os.Stdout, "close %s: %s\n", key, comment)
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(issuesCmd)
+	addDBFlags(issuesCmd.Flags())
+	err := issuesCmd.MarkFlagRequired("pass")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	issuesCmd.Flags().StringVarP(&repo, "repo", "g", "", "repository name")
+	err = issuesCmd.MarkFlagRequired("repo")
+	if err != nil {
+		log.Fatal(err)
+	}
+	issuesCmd.Flags().StringVar(&tracker, "tracker", gocop.TrackerGitHub, "issue tracker, one of github|jira")
+	issuesCmd.Flags().DurationVar(&issueWindow, "window", 30*24*time.Hour, "how far back stored runs are searched for flaky tests")
+	issuesCmd.Flags().IntVar(&stableRuns, "stable-runs", 20, "passing runs after a test was last flaky before its issue is closed")
+	issuesCmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the changes to issues without calling the tracker or storing them")
+	issuesCmd.Flags().StringSliceVar(&issueLabels, "labels", []string{"flaky-test"}, "comma-separated labels of filed issues")
+
+	issuesCmd.Flags().StringVar(&githubURL, "github-url", envOr("GITHUB_API_URL", "https://api.github.com"), "GitHub API URL")
+	issuesCmd.Flags().StringVar(&githubRepo, "github-repo", os.Getenv("GITHUB_REPOSITORY"), "GitHub repository issues are filed in, as owner/name")
+	issuesCmd.Flags().StringVar(&githubToken, "github-token", "", "GitHub token allowed to write issues, defaults to $GITHUB_TOKEN")
+
+	issuesCmd.Flags().StringVar(&jiraURL, "jira-url", os.Getenv("JIRA_URL"), "Jira base URL")
+	issuesCmd.Flags().StringVar(&jiraProject, "jira-project", "", "key of the Jira project issues are filed in")
+	issuesCmd.Flags().StringVar(&jiraIssueType, "jira-issue-type", "Bug", "type of filed Jira issues")
+	issuesCmd.Flags().StringVar(&jiraUser, "jira-user", os.Getenv("JIRA_USER"), "Jira user of the API token, or empty to send the token as a bearer token")
+	issuesCmd.Flags().StringVar(&jiraToken, "jira-token", "", "Jira API token, defaults to $JIRA_API_TOKEN")
+	issuesCmd.Flags().StringVar(&jiraCloseTransition, "jira-close-transition", "Done", "name of the Jira transition closing an issue")
+}
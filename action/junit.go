@@ -0,0 +1,59 @@
+package action
+
+import (
+	"io"
+	"log"
+	"os"
+
+	"github.com/digitalocean/gocop/gocop"
+	"github.com/spf13/cobra"
+)
+
+var junitOut string
+
+var junitCmd = &cobra.Command{
+	Use:   "junit",
+	Short: "converts test results to JUnit XML",
+	Long:  ``,
+	Run: func(cmd *cobra.Command, args []string) {
+		results := gocop.ParseFileResults(src)
+
+		var flaky gocop.FlakyPackages
+		if len(retests) > 0 {
+			flaky = gocop.FlakyFileReport(retests...)
+		}
+
+		writeJUnit(junitOut, gocop.NewJUnit(results, flaky))
+	},
+}
+
+// writeJUnit writes a JUnit XML report to path, or stdout when path is - or empty
+func writeJUnit(path string, report gocop.JUnitTestSuites) {
+	var w io.Writer = os.Stdout
+	if len(path) > 0 && path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	err := gocop.WriteJUnit(w, report)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(junitCmd)
+
+	junitCmd.Flags().StringVarP(&src, "src", "s", "", "source test output file, or - for stdin")
+	err := junitCmd.MarkFlagRequired("src")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	junitCmd.Flags().StringSliceVarP(&retests, "retests", "r", []string{}, "source output for retests used to annotate flaky tests")
+	junitCmd.Flags().StringVarP(&junitOut, "out", "o", "-", "file to write the JUnit XML report to")
+}
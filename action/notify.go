@@ -0,0 +1,92 @@
+package action
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"log"
+	"time"
+
+	"github.com/digitalocean/gocop/gocop"
+	"github.com/spf13/cobra"
+)
+
+var webhooks, protectedBranches []string
+var notifyTemplate string
+var notifyWindow time.Duration
+
+// addNotifyFlags registers the flags configuring notifications of changes in test health
+func addNotifyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&webhooks, "webhook", []string{}, "comma-separated webhooks to notify as kind=url, where kind is one of generic|slack|teams, defaults to $GOCOP_WEBHOOKS")
+	cmd.Flags().StringSliceVar(&protectedBranches, "protected-branch", []string{"master", "main"}, "comma-separated branches whose changes in test health are notified")
+	cmd.Flags().StringVar(&notifyTemplate, "notify-template", "", "file containing a Go text/template for the notification text")
+	cmd.Flags().DurationVar(&notifyWindow, "notify-flaky-window", 7*24*time.Hour, "how long before a flaky package is announced again")
+}
+
+// notify posts changes in the health of a protected branch to the configured webhooks
+//
+// Notifications are only recorded as announced once every webhook accepted them, so failed
+// deliveries are retried on the next run.
+func notify(cmd *cobra.Command, db *sql.DB, run gocop.TestRun, results gocop.PackageResults, flaky gocop.FlakyPackages) {
+	envListFlag(cmd, "webhook", "GOCOP_WEBHOOKS", &webhooks)
+	if len(webhooks) == 0 || !contains(protectedBranches, run.Branch) {
+		return
+	}
+
+	hooks := make([]gocop.Webhook, 0)
+	for _, s := range webhooks {
+		hook, err := gocop.ParseWebhook(s)
+		if err != nil {
+			log.Fatal(err)
+		}
+		hooks = append(hooks, hook)
+	}
+
+	text := ""
+	if notifyTemplate != "" {
+		content, err := ioutil.ReadFile(notifyTemplate)
+		if err != nil {
+			log.Fatal(err)
+		}
+		text = string(content)
+	}
+	tmpl, err := gocop.NewNotifyTemplate(text)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	announced, err := gocop.GetAnnouncements(db, run.Repo, run.Branch)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	notifications := gocop.NewNotifications(run, results, flaky, announced, notifyWindow)
+	if len(notifications) == 0 {
+		return
+	}
+
+	delivered := true
+	for _, hook := range hooks {
+		err = hook.Send(notifications, tmpl)
+		if err != nil {
+			log.Printf("unable to notify: %v", err)
+			delivered = false
+		}
+	}
+	if !delivered {
+		return
+	}
+
+	err = gocop.SaveAnnouncements(db, notifications)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package action
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"time"
+
+	"github.com/digitalocean/gocop/gocop"
+	"github.com/spf13/cobra"
+)
+
+var seed int64
+var victim string
+var orderRuns int
+
+var orderCmd = &cobra.Command{
+	Use:   "order <package>",
+	Short: "finds the tests a test fails after by replaying a failed -shuffle seed",
+	Long: `Compiles the test binary of a package and replays the test order of a failed go test -shuffle run with
+--seed, or with the seed of the latest stored failure of the package on --branch of --repo. The tests which ran
+before the failed test are then narrowed down by delta debugging to the polluters it fails after.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		binary, err := gocop.CompileTest(".", args[0], buildFlags())
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer binary.Remove()
+
+		if !cmd.Flags().Changed("seed") {
+			if repo == "" || password == "" {
+				log.Fatal("--seed, or --repo and --pass to look up the seed of a stored failure, is required")
+			}
+			seed = failingShuffle(binary.Package)
+		}
+
+		report, err := gocop.OrderSearch{
+			Binary:  binary,
+			Seed:    seed,
+			Victim:  victim,
+			Args:    testFlags(),
+			Runs:    orderRuns,
+			Timeout: testTimeout + time.Minute,
+		}.Run()
+		if err != nil {
+			log.Fatalf("%s with -shuffle %d: %v", binary.Package, seed, err)
+		}
+
+		writeReport(os.Stdout, report)
+	},
+}
+
+// failingShuffle looks up the seed of the latest stored failure of a package which ran with -shuffle
+func failingShuffle(pkg string) int64 {
+	var err error
+
+	db := connectDB()
+	defer func() {
+		err = db.Close()
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}()
+
+	s, err := gocop.GetFailingShuffle(db, repo, branch, pkg)
+	if err == sql.ErrNoRows {
+		log.Fatalf("no failure of %s on %s was stored with a -shuffle seed", pkg, branch)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	return s
+}
+
+func init() {
+	RootCmd.AddCommand(orderCmd)
+
+	orderCmd.Flags().Int64Var(&seed, "seed", 0, "seed of the failed test order, as printed by go test -shuffle")
+	orderCmd.Flags().StringVar(&victim, "victim", "", "failed test to find the polluters of, the first failed test unless set")
+	orderCmd.Flags().IntVar(&orderRuns, "runs", 1, "times each order is run before it is considered passing")
+	addTestFlags(orderCmd.Flags())
+	addOutputFlags(orderCmd.Flags())
+
+	orderCmd.Flags().StringVarP(&repo, "repo", "g", "", "repository name, looking up the seed of the latest failure when --seed is not set")
+	orderCmd.Flags().StringVarP(&branch, "branch", "b", "master", "branch name")
+	addDBFlags(orderCmd.Flags())
+}
@@ -0,0 +1,27 @@
+package action
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/digitalocean/gocop/gocop"
+	"github.com/spf13/pflag"
+)
+
+var output, outputTemplate string
+
+// addOutputFlags registers the flags selecting how a command writes its report
+func addOutputFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&output, "output", gocop.FormatText, fmt.Sprintf("output format, one of %s", strings.Join(gocop.Formats, "|")))
+	flags.StringVar(&outputTemplate, "template", "", "Go text/template used with --output template")
+}
+
+// writeReport writes a report in the format selected by the output flags
+func writeReport(w io.Writer, report gocop.Report) {
+	err := gocop.Write(w, output, outputTemplate, report)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
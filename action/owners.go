@@ -0,0 +1,30 @@
+package action
+
+import (
+	"log"
+
+	"github.com/digitalocean/gocop/gocop"
+	"github.com/spf13/pflag"
+)
+
+var owners bool
+var codeowners string
+
+// addOwnerFlags registers the flags resolving the owners of packages from CODEOWNERS
+func addOwnerFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&owners, "owners", false, "group packages by their owners in the CODEOWNERS file of the repository")
+	flags.StringVar(&codeowners, "codeowners", "", "CODEOWNERS file used with --owners, found in the repository of the module in the working directory by default")
+}
+
+// loadOwnership reads the owners of packages when selected by the owner flags, returning nil otherwise
+func loadOwnership() *gocop.Ownership {
+	if !owners && codeowners == "" {
+		return nil
+	}
+
+	o, err := gocop.LoadOwnership(".", codeowners)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return o
+}
@@ -0,0 +1,131 @@
+package action
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/gocop/gocop"
+	"github.com/digitalocean/gocop/server"
+	"github.com/spf13/cobra"
+)
+
+var serverURL, token string
+var parse bool
+
+var pushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "uploads test results to a gocop server for storage",
+	Long:  ``,
+	Run: func(cmd *cobra.Command, args []string) {
+		envFlag(cmd, "token", "GOCOP_TOKEN", &token)
+
+		run := newTestRun(cmd)
+		if run.BuildID == 0 {
+			log.Fatal("--build-id is required when it is not detected from the CI environment")
+		}
+		upload := server.Upload{
+			Run: server.Run{
+				Created:   run.Created,
+				BuildID:   run.BuildID,
+				Repo:      run.Repo,
+				Branch:    run.Branch,
+				Sha:       run.Sha,
+				Command:   run.Command,
+				Benchmark: run.Benchmark,
+				Short:     run.Short,
+				Race:      run.Race,
+				Tags:      run.Tags,
+				Metadata:  run.Metadata,
+				Flags:     &run.Flags,
+			},
+		}
+
+		if parse {
+			if len(src) > 0 {
+				upload.Results = gocop.ParseFileResults(src)
+			}
+			if len(retests) > 0 {
+				upload.Flaky = gocop.FlakyFileReport(retests...)
+			}
+		} else {
+			if len(src) > 0 {
+				upload.Output = readFile(src)
+			}
+			for _, retest := range retests {
+				upload.Retests = append(upload.Retests, readFile(retest))
+			}
+		}
+
+		body, err := json.Marshal(upload)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(serverURL, "/")+"/api/runs", bytes.NewReader(body))
+		if err != nil {
+			log.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		client := http.Client{Timeout: 5 * time.Minute}
+		res, err := client.Do(req)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer res.Body.Close()
+
+		content, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if res.StatusCode != http.StatusCreated {
+			var apiErr struct {
+				Error string `json:"error"`
+			}
+			if json.Unmarshal(content, &apiErr) == nil && apiErr.Error != "" {
+				log.Fatalf("upload failed: %s: %s", res.Status, apiErr.Error)
+			}
+			log.Fatalf("upload failed: %s", res.Status)
+		}
+
+		fmt.Printf("stored run created at %s\n", run.Created.Format(time.RFC3339Nano))
+	},
+}
+
+// readFile reads a whole file, or stdin for -
+func readFile(path string) string {
+	f, err := gocop.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	content, err := ioutil.ReadAll(f)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return string(content)
+}
+
+func init() {
+	RootCmd.AddCommand(pushCmd)
+
+	pushCmd.Flags().StringVar(&serverURL, "server", "", "base URL of the gocop server")
+	err := pushCmd.MarkFlagRequired("server")
+	if err != nil {
+		log.Fatal(err)
+	}
+	pushCmd.Flags().StringVar(&token, "token", "", "bearer token for the server, defaults to $GOCOP_TOKEN")
+	pushCmd.Flags().BoolVar(&parse, "parse", false, "parse output locally and upload the results rather than the raw output")
+
+	addRunFlags(pushCmd)
+	pushCmd.Flags().StringVarP(&src, "src", "s", "", "source test output file, or - for stdin")
+	pushCmd.Flags().StringSliceVarP(&retests, "rerun", "r", []string{}, "comma-separated source output for retests, or - for stdin")
+}
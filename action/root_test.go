@@ -0,0 +1,45 @@
+package action
+
+import (
+	"testing"
+
+	"github.com/poy/onpar"
+	"github.com/poy/onpar/expect"
+	"github.com/poy/onpar/matchers"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func TestFlagDefaults(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	o.Spec("every flag starts at the default its help shows", func(expect expect.Expectation) {
+		var commands []*cobra.Command
+		var walk func(*cobra.Command)
+		walk = func(cmd *cobra.Command) {
+			commands = append(commands, cmd)
+			for _, c := range cmd.Commands() {
+				walk(c)
+			}
+		}
+		walk(RootCmd)
+
+		for _, cmd := range commands {
+			cmd.Flags().VisitAll(func(f *pflag.Flag) {
+				expect(cmd.Name() + " --" + f.Name + "=" + f.Value.String()).To(matchers.Equal(cmd.Name() + " --" + f.Name + "=" + f.DefValue))
+			})
+		}
+	})
+
+	o.Spec("stress, bisect and verify keep their own run defaults", func(expect expect.Expectation) {
+		expect(stressRuns).To(matchers.Equal(100))
+		expect(maxFailureRate).To(matchers.Equal(0.0))
+		expect(verifyRuns).To(matchers.Equal(200))
+		expect(verifyMaxFailureRate).To(matchers.Equal(0.02))
+	})
+}
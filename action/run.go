@@ -0,0 +1,145 @@
+package action
+
+import (
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/gocop/gocop"
+	"github.com/spf13/cobra"
+)
+
+var repo, branch, sha, start, runCommand string
+var buildID int64
+var bench, short, race bool
+var tags []string
+var metadata map[string]string
+var detect bool
+
+// addRunFlags registers the flags describing a test run for storage
+func addRunFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&repo, "repo", "g", "", "repository name, detected unless set")
+	cmd.Flags().StringVarP(&branch, "branch", "b", "master", "branch name, detected unless set")
+	cmd.Flags().Int64VarP(&buildID, "build-id", "i", 0, "build id, detected unless set")
+
+	cmd.Flags().StringVarP(&runCommand, "cmd", "c", "", "test execution command, setting --bench, --short, --race and --tags when it runs go test")
+	cmd.Flags().StringVarP(&sha, "sha", "z", "", "git sha of test run, detected unless set")
+	cmd.Flags().StringVarP(&start, "time", "m", "", "time of test run")
+	cmd.Flags().BoolVar(&bench, "bench", false, "indicate if test ran benchmarks")
+	cmd.Flags().BoolVar(&short, "short", false, "indicate if test is run with -short flag")
+	cmd.Flags().BoolVar(&race, "race", false, "indicate if test is run with -race flag")
+	cmd.Flags().StringSliceVar(&tags, "tags", []string{}, "comma-separated tags enabled for the run")
+	cmd.Flags().StringToStringVar(&metadata, "metadata", map[string]string{}, "comma-separated key=value environment of the run, such as go_version=go1.12 or runner=linux-large")
+	addDetectFlag(cmd)
+}
+
+// addDetectFlag registers the flag detecting the run flags which are not set
+func addDetectFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&detect, "detect", true, "detect the repository, branch, sha, build id and metadata from git and CI environment variables")
+}
+
+// newTestRun describes the run selected by the run flags, detecting those which are not set with --detect
+func newTestRun(cmd *cobra.Command) gocop.TestRun {
+	run := gocop.TestRun{
+		BuildID:   buildID,
+		Repo:      repo,
+		Branch:    branch,
+		Sha:       sha,
+		Command:   runCommand,
+		Benchmark: bench,
+		Short:     short,
+		Race:      race,
+		Tags:      tags,
+		Metadata:  make(map[string]string),
+	}
+	for key, value := range metadata {
+		run.Metadata[key] = value
+	}
+
+	if runCommand != "" {
+		parseRunCommand(cmd, &run)
+	}
+
+	if detect {
+		detectRun(cmd, &run)
+	}
+
+	if len(start) != 0 {
+		var err error
+		run.Created, err = time.Parse(time.RFC3339, start)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		run.Created = time.Now().UTC()
+	}
+
+	return run
+}
+
+// detectRun fills the repository, branch, sha and build id of a run when their flags were not set, and the
+// metadata not set with --metadata
+func detectRun(cmd *cobra.Command, run *gocop.TestRun) {
+	env := gocop.DetectEnvironment(".", os.Environ())
+	flags := cmd.Flags()
+
+	if !flags.Changed("repo") && env.Repo != "" {
+		run.Repo = env.Repo
+	}
+	if !flags.Changed("branch") && env.Branch != "" {
+		run.Branch = env.Branch
+	}
+	if !flags.Changed("sha") && env.Sha != "" {
+		run.Sha = env.Sha
+	}
+	if !flags.Changed("build-id") && env.BuildID != 0 {
+		run.BuildID = env.BuildID
+	}
+	for key, value := range env.Metadata {
+		if _, ok := run.Metadata[key]; !ok {
+			run.Metadata[key] = value
+		}
+	}
+}
+
+// parseRunCommand configures a run from its go test command line, warning when the command disagrees with the
+// flags describing the run, which are kept
+func parseRunCommand(cmd *cobra.Command, run *gocop.TestRun) {
+	parsed, err := gocop.ParseTestCommand(run.Command)
+	if err == gocop.ErrNotGoTest {
+		return
+	}
+	if err != nil {
+		log.Printf("unable to parse --cmd: %v", err)
+		return
+	}
+	run.Flags = parsed
+
+	flags := cmd.Flags()
+	resolve := func(name string, explicit, fromCommand bool) bool {
+		if !flags.Changed(name) {
+			return fromCommand
+		}
+		if explicit != fromCommand {
+			log.Printf("--%s=%t conflicts with --cmd %q, keeping --%s=%t", name, explicit, run.Command, name, explicit)
+		}
+		return explicit
+	}
+	run.Benchmark = resolve("bench", run.Benchmark, parsed.Benchmark())
+	run.Short = resolve("short", run.Short, parsed.Short)
+	run.Race = resolve("race", run.Race, parsed.Race)
+
+	if !flags.Changed("tags") {
+		run.Tags = parsed.Tags
+	} else if strings.Join(sortedCopy(run.Tags), ",") != strings.Join(sortedCopy(parsed.Tags), ",") {
+		log.Printf("--tags=%s conflicts with --cmd %q, keeping --tags=%s", strings.Join(run.Tags, ","), run.Command, strings.Join(run.Tags, ","))
+	}
+}
+
+func sortedCopy(values []string) []string {
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,76 @@
+package action
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/gocop/server"
+	"github.com/spf13/cobra"
+)
+
+var listen string
+var tokens []string
+var metricsWindow time.Duration
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "serves run and flakiness history over an HTTP JSON API",
+	Long:  ``,
+	Run: func(cmd *cobra.Command, args []string) {
+		envListFlag(cmd, "token", "GOCOP_TOKENS", &tokens)
+
+		db := connectDB()
+		defer db.Close()
+
+		log.Printf("listening on %s", listen)
+		log.Fatal(http.ListenAndServe(listen, server.New(server.DBStore{DB: db}, server.Config{Tokens: tokens, MetricsWindow: metricsWindow})))
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(serveCmd)
+	addDBFlags(serveCmd.Flags())
+	err := serveCmd.MarkFlagRequired("pass")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	serveCmd.Flags().StringVarP(&listen, "listen", "l", ":8080", "address to listen on")
+	serveCmd.Flags().StringSliceVar(&tokens, "token", []string{}, "comma-separated bearer tokens accepted for uploads and quarantine changes, defaults to $GOCOP_TOKENS, both are disabled without any")
+	serveCmd.Flags().DurationVar(&metricsWindow, "metrics-window", server.DefaultMetricsWindow, "how far back stored history is aggregated into /metrics")
+}
+
+// envList splits a comma-separated environment variable
+func envList(key string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return []string{}
+	}
+	return strings.Split(value, ",")
+}
+
+// envFlag falls back to an environment variable for a flag which was not set, rather than using it as the flag
+// default, which help and usage errors would print
+func envFlag(cmd *cobra.Command, name, key string, value *string) {
+	if !cmd.Flags().Changed(name) {
+		*value = os.Getenv(key)
+	}
+}
+
+// envListFlag falls back to a comma-separated environment variable for a flag which was not set, as envFlag
+func envListFlag(cmd *cobra.Command, name, key string, values *[]string) {
+	if !cmd.Flags().Changed(name) {
+		*values = envList(key)
+	}
+}
+
+// envOr reads an environment variable, falling back to a default when unset
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,62 @@
+package action
+
+import (
+	"log"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/digitalocean/gocop/gocop"
+	"github.com/spf13/cobra"
+)
+
+var parallel, stressRuns, count int
+var stressDuration time.Duration
+
+var stressCmd = &cobra.Command{
+	Use:   "stress <package>",
+	Short: "runs the tests of a package repeatedly to measure how often they fail",
+	Long: `Compiles the test binary of a package once with go test -c and runs it repeatedly in parallel until
+--runs runs completed or --duration elapsed, reporting the failure rate with a confidence interval and the
+failures grouped by the tests and source locations they failed at.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if stressRuns <= 0 && stressDuration <= 0 {
+			log.Fatal("one of --runs or --duration is required")
+		}
+
+		binary, err := gocop.CompileTest(".", args[0], buildFlags())
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer binary.Remove()
+
+		report, err := gocop.Stress{
+			Binary:     binary,
+			Args:       testFlags(),
+			Parallel:   parallel,
+			Runs:       stressRuns,
+			Duration:   stressDuration,
+			Count:      count,
+			Timeout:    testTimeout + time.Minute,
+			Confidence: confidence,
+		}.Run()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		writeReport(os.Stdout, report)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(stressCmd)
+
+	stressCmd.Flags().IntVar(&parallel, "parallel", runtime.NumCPU(), "runs of the test binary at the same time")
+	stressCmd.Flags().IntVar(&stressRuns, "runs", 100, "stop after that many runs, or 0 to run until --duration")
+	stressCmd.Flags().DurationVar(&stressDuration, "duration", 0, "stop starting runs after that long")
+	stressCmd.Flags().IntVar(&count, "count", 1, "run the tests that many times in each run, as go test -count")
+	stressCmd.Flags().Float64Var(&confidence, "confidence", 0.95, "confidence level of the failure rate interval")
+	addTestFlags(stressCmd.Flags())
+	addOutputFlags(stressCmd.Flags())
+}
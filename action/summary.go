@@ -0,0 +1,114 @@
+package action
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/gocop/gocop"
+	"github.com/spf13/cobra"
+)
+
+// formatMarkdown renders the summary for pull request comments and job summaries
+const formatMarkdown = "markdown"
+
+var summaryFormat string
+var history, stepSummary bool
+var historyWindow time.Duration
+
+var summaryCmd = &cobra.Command{
+	Use:   "summary",
+	Short: "summarizes a test run for pull request comments",
+	Long:  ``,
+	Run: func(cmd *cobra.Command, args []string) {
+		results := gocop.ParseFileResults(src)
+
+		var flaky gocop.FlakyPackages
+		if len(retests) > 0 {
+			flaky = gocop.FlakyFileReport(retests...)
+		}
+		if o := loadOwnership(); o != nil {
+			results = o.Results(results)
+			flaky = o.Flaky(flaky)
+		}
+
+		var hist *gocop.History
+		if history {
+			hist = loadHistory(time.Now().UTC())
+		}
+		report := gocop.NewRunReport(results, flaky, hist)
+
+		var w io.Writer = os.Stdout
+		if stepSummary {
+			path := os.Getenv("GITHUB_STEP_SUMMARY")
+			if len(path) == 0 {
+				log.Fatal("GITHUB_STEP_SUMMARY is not set")
+			}
+
+			f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			w = f
+		}
+
+		if summaryFormat != formatMarkdown {
+			output = summaryFormat
+			writeReport(w, report)
+			return
+		}
+
+		content, err := report.Markdown()
+		if err != nil {
+			log.Fatal(err)
+		}
+		_, err = io.WriteString(w, content)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// loadHistory retrieves the previous results and known flakes of the branch from the database
+func loadHistory(before time.Time) *gocop.History {
+	db := connectDB()
+	defer db.Close()
+
+	previous, err := gocop.GetPreviousResults(db, repo, branch, before)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	flaky, err := gocop.GetFlakyPackages(db, repo, before.Add(-historyWindow))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return &gocop.History{Previous: previous, Flaky: flaky}
+}
+
+func init() {
+	RootCmd.AddCommand(summaryCmd)
+
+	summaryCmd.Flags().StringVarP(&src, "src", "s", "", "source test output file, or - for stdin")
+	err := summaryCmd.MarkFlagRequired("src")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	summaryCmd.Flags().StringSliceVarP(&retests, "retests", "r", []string{}, "source output for retests used to identify flaky packages")
+	summaryCmd.Flags().StringVar(&summaryFormat, "format", formatMarkdown, fmt.Sprintf("output format, one of %s|%s", formatMarkdown, strings.Join(gocop.Formats, "|")))
+	summaryCmd.Flags().StringVar(&outputTemplate, "template", "", "Go text/template used with --format template")
+	addOwnerFlags(summaryCmd.Flags())
+	summaryCmd.Flags().BoolVar(&stepSummary, "step-summary", false, "append the summary to the file named by $GITHUB_STEP_SUMMARY")
+
+	summaryCmd.Flags().BoolVar(&history, "history", false, "compare against runs stored in the database")
+	summaryCmd.Flags().DurationVar(&historyWindow, "history-window", 7*24*time.Hour, "how far back stored flaky results are considered known")
+	summaryCmd.Flags().StringVarP(&repo, "repo", "g", "", "repository name")
+	summaryCmd.Flags().StringVarP(&branch, "branch", "b", "master", "branch name")
+	addDBFlags(summaryCmd.Flags())
+}
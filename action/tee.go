@@ -0,0 +1,82 @@
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/digitalocean/gocop/gocop"
+	"github.com/spf13/cobra"
+)
+
+var out, rawLog string
+
+var teeCmd = &cobra.Command{
+	Use:   "tee",
+	Short: "passes test output through from stdin while parsing results",
+	Long: `Echoes test output read from stdin to stdout unchanged as it arrives,
+then prints a summary of the parsed package results to stderr. Exits 1 when
+any package failed and 2 when no package results were found.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var w io.Writer = os.Stdout
+		if len(rawLog) > 0 {
+			f, err := os.Create(rawLog)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			w = io.MultiWriter(os.Stdout, f)
+		}
+
+		results := make(gocop.PackageResults, 0)
+		scanner := gocop.NewScanner(io.TeeReader(os.Stdin, w))
+		for scanner.Scan() {
+			result, err := gocop.NewPackageResult(scanner.Result())
+			if err != nil {
+				log.Fatal(err)
+			}
+			results = append(results, result)
+		}
+		if err := scanner.Err(); err != nil {
+			log.Fatal(err)
+		}
+
+		summary := gocop.Summarize(results)
+		if len(out) > 0 {
+			content, err := json.MarshalIndent(struct {
+				Summary  gocop.Summary        `json:"summary"`
+				Packages gocop.PackageResults `json:"packages"`
+			}{summary, results}, "", "  ")
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			err = ioutil.WriteFile(out, content, 0644)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		writeReport(os.Stderr, summary)
+		if output == gocop.FormatText {
+			fmt.Fprintln(os.Stderr)
+		}
+		switch {
+		case summary.Packages == 0:
+			os.Exit(2)
+		case summary.Failed > 0:
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(teeCmd)
+
+	teeCmd.Flags().StringVarP(&out, "out", "o", "", "file to write parsed results to as JSON")
+	addOutputFlags(teeCmd.Flags())
+	teeCmd.Flags().StringVarP(&rawLog, "log", "l", "", "file to write a copy of the raw test output to")
+}
@@ -0,0 +1,88 @@
+package action
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"github.com/digitalocean/gocop/gocop"
+	"github.com/spf13/cobra"
+)
+
+var otlpEndpoint, serviceName, traceOut string
+var otlpHeaders []string
+
+var traceCmd = &cobra.Command{
+	Use:   "trace",
+	Short: "exports a test run as an OpenTelemetry trace",
+	Long:  `Exports a test run as a trace with a span for the run, each package and each test. Spans are timed by go test -json events, and estimated from durations for other input formats.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		f, err := gocop.Open(src)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+
+		root, err := gocop.ReadTrace(f, newTestRun(cmd))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// write the export request rather than sending it, for inspection or a collector's file receiver
+		if traceOut != "" {
+			w, err := os.Create(traceOut)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer w.Close()
+
+			err = gocop.WriteOTLP(w, serviceName, root)
+			if err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+
+		envListFlag(cmd, "header", "OTEL_EXPORTER_OTLP_HEADERS", &otlpHeaders)
+		headers := make(map[string]string)
+		for _, header := range otlpHeaders {
+			parts := strings.SplitN(header, "=", 2)
+			if len(parts) != 2 {
+				log.Fatalf("invalid header %q, expected key=value", header)
+			}
+			headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+
+		err = gocop.ExportOTLP(otlpEndpoint, headers, serviceName, root)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// defaultOTLPEndpoint follows the OpenTelemetry exporter environment variables
+func defaultOTLPEndpoint() string {
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); endpoint != "" {
+		return endpoint
+	}
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		return strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+	}
+	return gocop.DefaultOTLPEndpoint
+}
+
+func init() {
+	RootCmd.AddCommand(traceCmd)
+
+	traceCmd.Flags().StringVarP(&src, "src", "s", "", "source test output file, or - for stdin")
+	err := traceCmd.MarkFlagRequired("src")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	addRunFlags(traceCmd)
+	traceCmd.Flags().StringVar(&otlpEndpoint, "endpoint", defaultOTLPEndpoint(), "OTLP/HTTP traces endpoint")
+	traceCmd.Flags().StringSliceVar(&otlpHeaders, "header", []string{}, "comma-separated key=value headers sent to the endpoint, defaults to $OTEL_EXPORTER_OTLP_HEADERS")
+	traceCmd.Flags().StringVar(&serviceName, "service", "gocop", "service name of the trace")
+	traceCmd.Flags().StringVarP(&traceOut, "out", "o", "", "write the OTLP JSON export request to a file instead of sending it")
+}
@@ -0,0 +1,141 @@
+package action
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/gocop/gocop"
+	"github.com/spf13/cobra"
+)
+
+var gomaxprocs []int
+var cpu []string
+var shuffle bool
+var verifyRuns int
+var verifyMaxFailureRate float64
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify <package> [test]",
+	Short: "runs a quarantined package or test repeatedly to verify that it is no longer flaky",
+	Long: `Compiles the test binary of a package once with go test -c and runs the package, or only the given test,
+--runs times split between configurations varying GOMAXPROCS, -cpu and -shuffle. The fix is verified when the
+failure rate is at most --max-failure-rate with --confidence confidence, exiting with status 1 otherwise.
+
+With --repo the runs are stored as a verify run, which does not count towards the flakiness of CI builds, and
+a verified package or test is resolved in the quarantine.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		var test string
+		if len(args) > 1 {
+			test = args[1]
+		}
+		if repo != "" && password == "" {
+			log.Fatal("--pass is required to store the runs with --repo")
+		}
+
+		binary, err := gocop.CompileTest(".", args[0], buildFlags())
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer binary.Remove()
+
+		report, err := gocop.Verify{
+			Binary:         binary,
+			Test:           test,
+			Args:           testFlags(),
+			Runs:           verifyRuns,
+			Parallel:       parallel,
+			Configs:        verifyConfigs(),
+			Timeout:        testTimeout + time.Minute,
+			MaxFailureRate: verifyMaxFailureRate,
+			Confidence:     confidence,
+		}.Run()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		writeReport(os.Stdout, report)
+
+		if repo != "" {
+			storeVerify(cmd, report, cmd.CommandPath()+" "+strings.Join(args, " "))
+		}
+
+		if !report.Verified {
+			os.Exit(1)
+		}
+	},
+}
+
+// verifyConfigs combines the verify flags into the configurations runs are split between
+func verifyConfigs() []gocop.VerifyConfig {
+	procs := gomaxprocs
+	if len(procs) == 0 {
+		procs = []int{0}
+	}
+
+	configs := make([]gocop.VerifyConfig, 0)
+	for _, p := range procs {
+		configs = append(configs, gocop.VerifyConfig{GOMAXPROCS: p, CPU: strings.Join(cpu, ","), Shuffle: shuffle})
+	}
+	return configs
+}
+
+// storeVerify stores the runs of a verification and resolves the quarantine of a verified package or test
+func storeVerify(cmd *cobra.Command, report gocop.VerifyReport, command string) {
+	var err error
+
+	db := connectDB()
+	defer func() {
+		err = db.Close()
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}()
+
+	run := newTestRun(cmd)
+	run.Kind = gocop.RunKindVerify
+	run.Command = command
+
+	results, flaky := report.Results()
+	err = gocop.StoreRun(db, run, results, flaky)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if !report.Verified {
+		return
+	}
+
+	resolved, err := gocop.ResolveQuarantine(db, repo, report.Package, report.Test, run.Created)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if resolved {
+		fmt.Println("resolved the quarantine of", report.Package, report.Test)
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().IntVar(&verifyRuns, "runs", 200, "runs split between the configurations")
+	verifyCmd.Flags().IntVar(&parallel, "parallel", runtime.NumCPU(), "runs of the test binary at the same time")
+	verifyCmd.Flags().IntSliceVar(&gomaxprocs, "gomaxprocs", []int{}, "comma-separated GOMAXPROCS values, each a configuration")
+	verifyCmd.Flags().StringSliceVar(&cpu, "cpu", []string{}, "comma-separated GOMAXPROCS values every run goes through, as go test -cpu")
+	verifyCmd.Flags().BoolVar(&shuffle, "shuffle", false, "randomize the order of tests and benchmarks, as go test -shuffle=on")
+	verifyCmd.Flags().Float64Var(&verifyMaxFailureRate, "max-failure-rate", 0.02, "highest failure rate of a fixed package or test")
+	verifyCmd.Flags().Float64Var(&confidence, "confidence", 0.95, "confidence level of the failure rate bound")
+	addTestFlags(verifyCmd.Flags())
+	addOutputFlags(verifyCmd.Flags())
+
+	verifyCmd.Flags().StringVarP(&repo, "repo", "g", "", "repository name, storing the runs and resolving the quarantine when set")
+	verifyCmd.Flags().StringVarP(&branch, "branch", "b", "master", "branch name")
+	verifyCmd.Flags().StringVarP(&sha, "sha", "z", "", "git sha of the verified fix")
+	verifyCmd.Flags().Int64VarP(&buildID, "build-id", "i", 0, "build id")
+	addDetectFlag(verifyCmd)
+	addDBFlags(verifyCmd.Flags())
+}
@@ -2,6 +2,7 @@ package main_test
 
 import (
 	"log"
+	"os"
 	"os/exec"
 	"testing"
 
@@ -28,13 +29,13 @@ func TestFailedPackages(t *testing.T) {
 			name:   "finds multiple failed packages",
 			action: "failed",
 			input:  "gocop/testdata/run0.txt",
-			want:   "github.com/digitalocean/gocop/sample/fail\ngithub.com/digitalocean/gocop/sample/failbuild\ngithub.com/digitalocean/gocop/sample/flaky",
+			want:   "github.com/digitalocean/gocop/sample/fail\ngithub.com/digitalocean/gocop/sample/failbuild\ngithub.com/digitalocean/gocop/sample/flaky\n",
 		},
 		{
 			name:   "finds single failed packages",
 			action: "failed",
 			input:  "gocop/testdata/run1.txt",
-			want:   "github.com/digitalocean/gocop/sample/fail\ngithub.com/digitalocean/gocop/sample/failbuild",
+			want:   "github.com/digitalocean/gocop/sample/fail\ngithub.com/digitalocean/gocop/sample/failbuild\n",
 		},
 	}
 
@@ -72,7 +73,7 @@ func TestFlakyPackages(t *testing.T) {
 		{
 			name: "finds single failed packages",
 			args: []string{"run", "main.go", "flaky", "-r", "gocop/testdata/run0.txt", "-r", "gocop/testdata/run1.txt", "-r", "gocop/testdata/run2.txt", "-r", "gocop/testdata/run3.txt"},
-			want: "github.com/digitalocean/gocop/sample/flaky",
+			want: "github.com/digitalocean/gocop/sample/flaky\n",
 		},
 	}
 
@@ -87,3 +88,29 @@ func TestFlakyPackages(t *testing.T) {
 		})
 	}
 }
+
+func TestFailedPackagesStdin(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	o.Spec("reads test output from stdin", func(expect expect.Expectation) {
+		f, err := os.Open("gocop/testdata/run1.txt")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+
+		cmd := exec.Command("go", "run", "main.go", "failed", "-s", "-")
+		cmd.Stdin = f
+		got, err := cmd.Output()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		expect(string(got)).To(Equal("github.com/digitalocean/gocop/sample/fail\ngithub.com/digitalocean/gocop/sample/failbuild\n"))
+	})
+}
@@ -7,5 +7,6 @@ require (
 	github.com/lib/pq v1.2.0
 	github.com/poy/onpar v0.0.0-20181125144932-f2f06780798d
 	github.com/spf13/cobra v0.0.3
-	github.com/spf13/pflag v1.0.3 // indirect
+	github.com/spf13/pflag v1.0.3
+	gopkg.in/yaml.v2 v2.4.0
 )
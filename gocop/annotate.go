@@ -0,0 +1,276 @@
+package gocop
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// AnnotationError marks an annotation for a failure
+	AnnotationError = "error"
+	// AnnotationWarning marks an annotation for a flaky test
+	AnnotationWarning = "warning"
+)
+
+// SourcePattern provides the REGEX pattern to find a file and line reported in test or build output
+const SourcePattern = `^\s*([^\s:]+\.go):(\d+)(?::\d+)?:\s*(.*)$`
+
+var sourceRe = regexp.MustCompile(SourcePattern)
+
+// Annotation reports a problem at a source location for display by a CI system
+type Annotation struct {
+	Level   string `json:"level"`
+	Package string `json:"package"`
+	Test    string `json:"test,omitempty"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Title   string `json:"title"`
+	Message string `json:"message"`
+}
+
+// Annotator is implemented by reports which can be written as CI annotations
+type Annotator interface {
+	Annotations() []Annotation
+}
+
+// Annotations reports an error for each failed test, or for the package when no test failed
+func (p PackageResults) Annotations() []Annotation {
+	annotations := make([]Annotation, 0)
+	for _, result := range p {
+		if result.Outcome != ResultFail {
+			continue
+		}
+
+		failed := false
+		for _, test := range result.Tests {
+			if test.Outcome == ResultFail {
+				failed = true
+				a := newAnnotation(AnnotationError, result.Package, test.Name, test.Output)
+				a.Title = test.Name + " failed"
+				annotations = append(annotations, a)
+			}
+		}
+		if failed {
+			continue
+		}
+
+		// build failures report every compiler error
+		for _, line := range result.Output {
+			if sourceRe.MatchString(line) {
+				a := newAnnotation(AnnotationError, result.Package, "", []string{line})
+				a.Title = result.Package + " " + statusOr(result.Status, "failed")
+				annotations = append(annotations, a)
+				failed = true
+			}
+		}
+		if !failed {
+			annotations = append(annotations, Annotation{
+				Level:   AnnotationError,
+				Package: result.Package,
+				Title:   result.Package + " " + statusOr(result.Status, "failed"),
+				Message: strings.Join(result.Output, "\n"),
+			})
+		}
+	}
+
+	return annotations
+}
+
+// Annotations reports a warning for each flaky test, or for the package when no test was identified
+func (f FlakyPackages) Annotations() []Annotation {
+	annotations := make([]Annotation, 0)
+	for _, pkg := range f {
+		if len(pkg.Tests) == 0 {
+			annotations = append(annotations, Annotation{
+				Level:   AnnotationWarning,
+				Package: pkg.Package,
+				Title:   pkg.Package + " is flaky",
+				Message: fmt.Sprintf("failed %d of %d attempts", pkg.Failures, pkg.Attempts),
+			})
+			continue
+		}
+
+		for _, test := range pkg.Tests {
+			a := newAnnotation(AnnotationWarning, pkg.Package, test.Name, testOutput(pkg.Runs, test.Name))
+			a.Title = test.Name + " is flaky"
+			a.Message = fmt.Sprintf("failed %d of %d attempts: %s", test.Failures, test.Attempts, a.Message)
+			annotations = append(annotations, a)
+		}
+	}
+
+	return annotations
+}
+
+// newAnnotation locates the first file and line reported in output
+func newAnnotation(level, pkg, test string, output []string) Annotation {
+	a := Annotation{Level: level, Package: pkg, Test: test}
+	for _, line := range output {
+		match := sourceRe.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		a.File = strings.Replace(match[1], `\`, "/", -1)
+		a.Line, _ = strconv.Atoi(match[2])
+		a.Message = match[3]
+		return a
+	}
+
+	a.Message = strings.TrimSpace(strings.Join(output, "\n"))
+	return a
+}
+
+// testOutput finds the output of the first failed attempt of a test
+func testOutput(runs []PackageResult, name string) []string {
+	for _, run := range runs {
+		for _, test := range run.Tests {
+			if test.Name == name && test.Outcome == ResultFail {
+				return test.Output
+			}
+		}
+	}
+	return nil
+}
+
+func statusOr(status, fallback string) string {
+	if status != "" {
+		return status
+	}
+	return fallback
+}
+
+// ModulePath reads the module path from the go.mod file in dir
+func ModulePath(dir string) (string, error) {
+	f, err := os.Open(path.Join(dir, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", fmt.Errorf("no module path found in %s", path.Join(dir, "go.mod"))
+}
+
+// SourcePath resolves a file reported in the output of a package to a path relative to the module root
+func SourcePath(module, pkg, file string) string {
+	// compilers report paths relative to the working directory, tests report only the file name
+	if strings.Contains(file, "/") {
+		return file
+	}
+
+	dir := pkg
+	switch {
+	case pkg == module:
+		dir = ""
+	case module != "" && strings.HasPrefix(pkg, module+"/"):
+		dir = strings.TrimPrefix(pkg, module+"/")
+	}
+
+	return path.Join(dir, file)
+}
+
+// writeGitHub writes annotations as GitHub Actions workflow commands
+func writeGitHub(w io.Writer, module string, annotations []Annotation) error {
+	for _, a := range annotations {
+		props := make([]string, 0)
+		if a.File != "" {
+			props = append(props, "file="+escapeGitHubProperty(SourcePath(module, a.Package, a.File)))
+			props = append(props, "line="+strconv.Itoa(a.Line))
+		}
+		props = append(props, "title="+escapeGitHubProperty(a.Title))
+
+		_, err := fmt.Fprintf(w, "::%s %s::%s\n", a.Level, strings.Join(props, ","), escapeGitHubData(a.Message))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// CodeQualityIssue is a single entry of a GitLab code quality report
+type CodeQualityIssue struct {
+	Description string              `json:"description"`
+	CheckName   string              `json:"check_name"`
+	Fingerprint string              `json:"fingerprint"`
+	Severity    string              `json:"severity"`
+	Location    CodeQualityLocation `json:"location"`
+}
+
+// CodeQualityLocation is the source location of a GitLab code quality issue
+type CodeQualityLocation struct {
+	Path  string           `json:"path"`
+	Lines CodeQualityLines `json:"lines"`
+}
+
+// CodeQualityLines is the line range of a GitLab code quality issue
+type CodeQualityLines struct {
+	Begin int `json:"begin"`
+}
+
+// writeGitLab writes annotations as a GitLab code quality report
+func writeGitLab(w io.Writer, module string, annotations []Annotation) error {
+	issues := make([]CodeQualityIssue, 0)
+	for _, a := range annotations {
+		severity := "major"
+		if a.Level == AnnotationWarning {
+			severity = "minor"
+		}
+
+		// fingerprints stay stable across runs so GitLab can track an issue between pipelines
+		key := a.Level + a.Package + a.Test + a.File
+		if a.Test == "" && a.Level == AnnotationError {
+			key += a.Message
+		}
+		sum := md5.Sum([]byte(key))
+		issue := CodeQualityIssue{
+			Description: strings.TrimSpace(a.Title + ": " + a.Message),
+			CheckName:   "gocop-" + a.Level,
+			Fingerprint: hex.EncodeToString(sum[:]),
+			Severity:    severity,
+			Location: CodeQualityLocation{
+				Path:  SourcePath(module, a.Package, a.File),
+				Lines: CodeQualityLines{Begin: a.Line},
+			},
+		}
+		if issue.Location.Lines.Begin == 0 {
+			issue.Location.Lines.Begin = 1
+		}
+		issues = append(issues, issue)
+	}
+
+	content, err := json.MarshalIndent(issues, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", content)
+	return err
+}
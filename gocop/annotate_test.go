@@ -0,0 +1,78 @@
+package gocop
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/poy/onpar"
+	"github.com/poy/onpar/expect"
+	"github.com/poy/onpar/matchers"
+)
+
+func TestSourcePath(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	tests := []struct {
+		name string
+		pkg  string
+		file string
+		want string
+	}{
+		{
+			name: "joins test file to package directory",
+			pkg:  "github.com/digitalocean/gocop/sample/fail",
+			file: "failing_test.go",
+			want: "sample/fail/failing_test.go",
+		},
+		{
+			name: "keeps compiler paths relative to the module",
+			pkg:  "github.com/digitalocean/gocop/sample/failbuild",
+			file: "sample/failbuild/broken.go",
+			want: "sample/failbuild/broken.go",
+		},
+		{
+			name: "joins test file to module root",
+			pkg:  "github.com/digitalocean/gocop",
+			file: "component_test.go",
+			want: "component_test.go",
+		},
+	}
+
+	for _, tt := range tests {
+		o.Spec(tt.name, func(expect expect.Expectation) {
+			got := SourcePath("github.com/digitalocean/gocop", tt.pkg, tt.file)
+			expect(got).To(matchers.Equal(tt.want))
+		})
+	}
+}
+
+func TestWriteGitHub(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	o.Spec("writes an error for each failed test", func(expect expect.Expectation) {
+		results := PackageResults{{
+			Package: "github.com/digitalocean/gocop/sample/fail",
+			Outcome: ResultFail,
+			Tests: []TestCase{{
+				Name:    "TestWillFail",
+				Outcome: ResultFail,
+				Output:  []string{"    failing_test.go:11: number does equal eleven", "    100% wrong"},
+			}},
+		}}
+
+		var buf bytes.Buffer
+		err := writeGitHub(&buf, "github.com/digitalocean/gocop", results.Annotations())
+		expect(err).To(matchers.BeNil())
+		expect(buf.String()).To(matchers.Equal("::error file=sample/fail/failing_test.go,line=11,title=TestWillFail failed::number does equal eleven\n"))
+	})
+}
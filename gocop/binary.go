@@ -0,0 +1,107 @@
+package gocop
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNoTests reports a package without a test binary, having no test files
+var ErrNoTests = errors.New("package has no tests")
+
+// BuildError reports a test binary which failed to compile, with the compiler output
+type BuildError struct {
+	Output string
+}
+
+func (e BuildError) Error() string {
+	return "build failed: " + e.Output
+}
+
+// TestBinary is the test binary of a package, compiled once to be run repeatedly
+type TestBinary struct {
+	Package string
+	Path    string
+	// Dir is the directory of the package, which tests run in
+	Dir string
+}
+
+// ResolvePackage resolves a package pattern to the import path and directory of the single package it matches,
+// rejecting patterns such as ./... which match several
+func ResolvePackage(dir, pkg string) (string, string, error) {
+	out, err := command(dir, "go", "list", "-f", "{{.ImportPath}}\n{{.Dir}}", pkg)
+	if err != nil {
+		return "", "", err
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 {
+		return "", "", errors.New("unable to resolve a single package for " + pkg)
+	}
+	return lines[0], lines[1], nil
+}
+
+// CompileTest compiles the test binary of a package with go test -c and flags such as -race or -tags
+func CompileTest(dir, pkg string, flags []string) (*TestBinary, error) {
+	importPath, pkgDir, err := ResolvePackage(dir, pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := ioutil.TempDir("", "gocop")
+	if err != nil {
+		return nil, err
+	}
+	binary := &TestBinary{Package: importPath, Path: filepath.Join(tmp, "test"), Dir: pkgDir}
+
+	args := append([]string{"test", "-c", "-o", binary.Path}, flags...)
+	cmd := exec.Command("go", append(args, pkg)...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		_ = os.RemoveAll(tmp)
+		if _, ok := err.(*exec.ExitError); ok {
+			return nil, BuildError{Output: strings.TrimSpace(string(output))}
+		}
+		return nil, err
+	}
+
+	if _, err = os.Stat(binary.Path); os.IsNotExist(err) {
+		_ = os.RemoveAll(tmp)
+		return nil, ErrNoTests
+	}
+	return binary, nil
+}
+
+// Run runs the test binary once with test flags such as -test.run, reporting whether the tests passed
+//
+// The binary is killed after timeout when set, which is reported as a failure.
+func (b *TestBinary) Run(args []string, env []string, timeout time.Duration) (bool, []byte, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, b.Path, args...)
+	cmd.Dir = b.Dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	out, err := cmd.CombinedOutput()
+	if _, ok := err.(*exec.ExitError); ok {
+		return false, out, nil
+	}
+	return err == nil, out, err
+}
+
+// Remove deletes the test binary
+func (b *TestBinary) Remove() error {
+	return os.RemoveAll(filepath.Dir(b.Path))
+}
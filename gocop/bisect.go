@@ -0,0 +1,140 @@
+package gocop
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// BisectGood marks a commit at which the package fails no more than accepted
+	BisectGood = "good"
+	// BisectBad marks a commit at which the package fails more than accepted
+	BisectBad = "bad"
+	// BisectSkip marks a commit which cannot be tested, such as when the package does not build
+	BisectSkip = "skip"
+)
+
+// BisectOracle decides whether a commit is good or bad by running the tests of a package repeatedly
+//
+// A commit is bad once the lower bound of the confidence interval of its failure rate exceeds MaxFailureRate,
+// so a flaky regression is found by running enough times to observe its failures.
+type BisectOracle struct {
+	Dir     string
+	Package string
+	// BuildFlags are passed to go test -c, such as -race or -tags
+	BuildFlags []string
+	// TestFlags are passed to the test binary, such as -test.run
+	TestFlags      []string
+	Runs           int
+	MaxFailureRate float64
+	Confidence     float64
+	// Timeout limits a single run of the test binary
+	Timeout time.Duration
+}
+
+// BisectVerdict is the outcome of testing a commit
+type BisectVerdict struct {
+	Verdict  string  `json:"verdict"`
+	Runs     int     `json:"runs"`
+	Failures int     `json:"failures"`
+	Low      float64 `json:"low"`
+	High     float64 `json:"high"`
+	Reason   string  `json:"reason,omitempty"`
+}
+
+// Test runs the tests of the package at the checked out commit, stopping early once the commit is known bad
+func (o BisectOracle) Test() (BisectVerdict, error) {
+	binary, err := CompileTest(o.Dir, o.Package, o.BuildFlags)
+	switch e := err.(type) {
+	case nil:
+	case BuildError:
+		return BisectVerdict{Verdict: BisectSkip, Reason: firstLine(e.Output)}, nil
+	default:
+		if err == ErrNoTests {
+			return BisectVerdict{Verdict: BisectSkip, Reason: err.Error()}, nil
+		}
+		return BisectVerdict{}, err
+	}
+	defer binary.Remove()
+
+	args := append([]string{"-test.count=1"}, o.TestFlags...)
+	v := BisectVerdict{Verdict: BisectGood}
+	for v.Runs < o.Runs {
+		passed, _, err := binary.Run(args, nil, o.Timeout)
+		if err != nil {
+			return v, err
+		}
+
+		v.Runs++
+		if !passed {
+			v.Failures++
+		}
+		v.Low, v.High = WilsonInterval(v.Failures, v.Runs, o.Confidence)
+		if v.Low > o.MaxFailureRate {
+			v.Verdict = BisectBad
+			break
+		}
+	}
+
+	return v, nil
+}
+
+// ExitCode is the exit status reporting the verdict to git bisect run
+func (v BisectVerdict) ExitCode() int {
+	switch v.Verdict {
+	case BisectBad:
+		return 1
+	case BisectSkip:
+		return 125
+	}
+	return 0
+}
+
+// Text describes the verdict in a single line
+func (v BisectVerdict) Text() string {
+	if v.Verdict == BisectSkip {
+		return "skip: " + v.Reason
+	}
+	return fmt.Sprintf("%s: %d of %d runs failed, failure rate between %.1f%% and %.1f%%",
+		v.Verdict, v.Failures, v.Runs, v.Low*100, v.High*100)
+}
+
+// Bisect runs git bisect between a good and a bad commit with step as the command testing each commit,
+// returning the first bad commit
+//
+// The bisect is reset afterwards, restoring the commit checked out before.
+func Bisect(dir, good, bad string, step []string, out io.Writer) (string, error) {
+	git := func(args ...string) error {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Stdout = out
+		cmd.Stderr = out
+		return cmd.Run()
+	}
+
+	err := git("bisect", "start", bad, good)
+	if err != nil {
+		return "", fmt.Errorf("git bisect start: %v", err)
+	}
+	defer func() {
+		_ = git("bisect", "reset")
+	}()
+
+	err = git(append([]string{"bisect", "run"}, step...)...)
+	if err != nil {
+		return "", fmt.Errorf("git bisect run: %v", err)
+	}
+
+	sha, err := command(dir, "git", "rev-parse", "refs/bisect/bad")
+	return strings.TrimSpace(sha), err
+}
+
+func firstLine(s string) string {
+	if i := strings.Index(s, "\n"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
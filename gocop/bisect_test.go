@@ -0,0 +1,102 @@
+package gocop
+
+import (
+	"math"
+	"testing"
+
+	"github.com/poy/onpar"
+	"github.com/poy/onpar/expect"
+	"github.com/poy/onpar/matchers"
+)
+
+func TestStats(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	round := func(f float64) float64 {
+		return math.Round(f*10000) / 10000
+	}
+
+	o.Spec("estimates failure rates", func(expect expect.Expectation) {
+		expect(round(ZScore(0.95))).To(matchers.Equal(1.96))
+
+		low, high := WilsonInterval(0, 10, 0.95)
+		expect(low).To(matchers.Equal(0.0))
+		expect(round(high)).To(matchers.Equal(0.2775))
+
+		low, high = WilsonInterval(5, 10, 0.95)
+		expect(round(low)).To(matchers.Equal(0.2366))
+		expect(round(high)).To(matchers.Equal(0.7634))
+
+		// rounding must not move the exact bounds, or passing commits would be bad
+		for runs := 1; runs <= 200; runs++ {
+			low, high = WilsonInterval(0, runs, 0.95)
+			expect(low).To(matchers.Equal(0.0))
+			low, high = WilsonInterval(runs, runs, 0.95)
+			expect(high).To(matchers.Equal(1.0))
+		}
+
+		low, high = WilsonInterval(0, 0, 0.95)
+		expect([]float64{low, high}).To(matchers.Equal([]float64{0, 1}))
+	})
+
+	o.Spec("chooses runs to observe failures", func(expect expect.Expectation) {
+		expect(RunsToDetect(0.2, 0.95)).To(matchers.Equal(14))
+		expect(RunsToDetect(1, 0.95)).To(matchers.Equal(1))
+		expect(RunsToDetect(0, 0.95)).To(matchers.Equal(0))
+	})
+}
+
+func TestBisectOracle(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles test binaries")
+	}
+
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	oracle := func(pkg string) BisectOracle {
+		return BisectOracle{
+			Dir:        ".",
+			Package:    "github.com/digitalocean/gocop/sample/" + pkg,
+			BuildFlags: []string{"-tags", "sample"},
+			Runs:       3,
+			Confidence: 0.95,
+		}
+	}
+
+	o.Spec("accepts passing packages", func(expect expect.Expectation) {
+		v, err := oracle("pass").Test()
+		expect(err).To(matchers.BeNil())
+		expect(v.Verdict).To(matchers.Equal(BisectGood))
+		expect(v.Runs).To(matchers.Equal(3))
+		expect(v.ExitCode()).To(matchers.Equal(0))
+	})
+
+	o.Spec("stops at the first failure of failing packages", func(expect expect.Expectation) {
+		v, err := oracle("fail").Test()
+		expect(err).To(matchers.BeNil())
+		expect(v.Verdict).To(matchers.Equal(BisectBad))
+		expect(v.Runs).To(matchers.Equal(1))
+		expect(v.ExitCode()).To(matchers.Equal(1))
+	})
+
+	o.Spec("skips packages which do not build", func(expect expect.Expectation) {
+		v, err := oracle("failbuild").Test()
+		expect(err).To(matchers.BeNil())
+		expect(v.Verdict).To(matchers.Equal(BisectSkip))
+		expect(v.ExitCode()).To(matchers.Equal(125))
+
+		v, err = oracle("numbers").Test()
+		expect(err).To(matchers.BeNil())
+		expect(v.Reason).To(matchers.Equal(ErrNoTests.Error()))
+	})
+}
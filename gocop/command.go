@@ -0,0 +1,190 @@
+package gocop
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotGoTest reports a command line which does not run go test
+var ErrNotGoTest = errors.New("command does not run go test")
+
+// TestFlags is the configuration of a run parsed from its go test command line
+type TestFlags struct {
+	Race     bool     `json:"race,omitempty"`
+	Short    bool     `json:"short,omitempty"`
+	Bench    string   `json:"bench,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Count    int      `json:"count,omitempty"`
+	CPU      string   `json:"cpu,omitempty"`
+	Timeout  string   `json:"timeout,omitempty"`
+	Run      string   `json:"run,omitempty"`
+	Shuffle  string   `json:"shuffle,omitempty"`
+	CoverPkg []string `json:"coverpkg,omitempty"`
+	Packages []string `json:"packages,omitempty"`
+}
+
+// valueFlags are the go test and build flags which take a value, so a value given as the next argument is not
+// mistaken for a package
+var valueFlags = map[string]bool{
+	"C": true, "asmflags": true, "bench": true, "benchtime": true, "blockprofile": true, "blockprofilerate": true,
+	"buildmode": true, "buildvcs": true, "compiler": true, "count": true, "covermode": true, "coverpkg": true,
+	"coverprofile": true, "cpu": true, "cpuprofile": true, "exec": true, "fuzz": true, "fuzzminimizetime": true,
+	"fuzztime": true, "gccgoflags": true, "gcflags": true, "installsuffix": true, "ldflags": true, "list": true,
+	"memprofile": true, "memprofilerate": true, "mod": true, "modfile": true, "mutexprofile": true,
+	"mutexprofilefraction": true, "o": true, "outputdir": true, "overlay": true, "p": true, "parallel": true,
+	"pgo": true, "pkgdir": true, "run": true, "shuffle": true, "skip": true, "tags": true, "timeout": true,
+	"toolexec": true, "trace": true, "vet": true,
+}
+
+// ParseTestCommand parses the flags and package patterns of the go test invocation in a command line
+//
+// The invocation may follow environment variables or wrappers, as in CGO_ENABLED=1 go test ./..., or be
+// passed after -- to gotestsum. Parsing stops at shell operators such as | and at -args.
+func ParseTestCommand(command string) (TestFlags, error) {
+	var flags TestFlags
+
+	args := splitCommand(command)
+	start := -1
+	for i, arg := range args {
+		if filepath.Base(arg) == "go" && i+1 < len(args) && args[i+1] == "test" {
+			start = i + 2
+			break
+		}
+		if filepath.Base(arg) == "gotestsum" {
+			for j := i + 1; j < len(args) && !isOperator(args[j]); j++ {
+				if args[j] == "--" {
+					start = j + 1
+					break
+				}
+			}
+			if start >= 0 {
+				break
+			}
+		}
+	}
+	if start < 0 {
+		return flags, ErrNotGoTest
+	}
+
+	for i := start; i < len(args); i++ {
+		arg := args[i]
+		if isOperator(arg) || arg == "-args" || arg == "--args" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			flags.Packages = append(flags.Packages, arg)
+			continue
+		}
+
+		name := strings.TrimPrefix(strings.TrimLeft(arg, "-"), "test.")
+		value := ""
+		hasValue := false
+		if j := strings.Index(name, "="); j >= 0 {
+			name, value, hasValue = name[:j], name[j+1:], true
+		}
+		if !hasValue && valueFlags[name] {
+			if i+1 >= len(args) {
+				return flags, fmt.Errorf("flag needs an argument: -%s", name)
+			}
+			i++
+			value, hasValue = args[i], true
+		}
+
+		var err error
+		switch name {
+		case "race":
+			flags.Race, err = parseBoolFlag(value, hasValue)
+		case "short":
+			flags.Short, err = parseBoolFlag(value, hasValue)
+		case "bench":
+			flags.Bench = value
+		case "tags":
+			flags.Tags = strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' })
+		case "count":
+			flags.Count, err = strconv.Atoi(value)
+		case "cpu":
+			flags.CPU = value
+		case "timeout":
+			_, err = time.ParseDuration(value)
+			flags.Timeout = value
+		case "run":
+			flags.Run = value
+		case "shuffle":
+			flags.Shuffle = value
+		case "coverpkg":
+			flags.CoverPkg = strings.Split(value, ",")
+		}
+		if err != nil {
+			return flags, fmt.Errorf("invalid value %q for flag -%s: %v", value, name, err)
+		}
+	}
+
+	return flags, nil
+}
+
+// Benchmark reports whether the command ran benchmarks
+func (f TestFlags) Benchmark() bool {
+	return f.Bench != ""
+}
+
+func parseBoolFlag(value string, hasValue bool) (bool, error) {
+	if !hasValue {
+		return true, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// isOperator reports whether an argument ends a command in a shell command line, such as a pipe or redirection
+func isOperator(arg string) bool {
+	switch arg {
+	case "|", "||", "&&", ";", "&":
+		return true
+	}
+	return strings.HasPrefix(arg, ">") || strings.HasPrefix(arg, "<") || strings.HasPrefix(arg, "2>") ||
+		strings.HasPrefix(arg, "1>")
+}
+
+// splitCommand splits a command line into arguments, removing the quotes and backslash escapes a shell would
+func splitCommand(command string) []string {
+	args := make([]string, 0)
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+
+	for _, r := range command {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args
+}
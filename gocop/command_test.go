@@ -0,0 +1,64 @@
+package gocop
+
+import (
+	"testing"
+
+	"github.com/poy/onpar"
+	"github.com/poy/onpar/expect"
+	"github.com/poy/onpar/matchers"
+)
+
+func TestParseTestCommand(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	tests := []struct {
+		name    string
+		command string
+		want    TestFlags
+	}{
+		{
+			name:    "parses flags and packages",
+			command: "go test -race -short -tags=integration,sample -count 1 -cpu 1,4 -timeout 5m -run 'TestA|TestB' -shuffle=on -coverpkg ./a,./b ./... ./cmd",
+			want: TestFlags{
+				Race: true, Short: true, Tags: []string{"integration", "sample"}, Count: 1, CPU: "1,4", Timeout: "5m",
+				Run: "TestA|TestB", Shuffle: "on", CoverPkg: []string{"./a", "./b"}, Packages: []string{"./...", "./cmd"},
+			},
+		},
+		{
+			name:    "finds go test after environment variables and stops at shell operators",
+			command: `CGO_ENABLED=1 /usr/local/go/bin/go test -v --race=false -bench . -tags "a b" ./... 2>&1 | tee out.txt`,
+			want:    TestFlags{Bench: ".", Tags: []string{"a", "b"}, Packages: []string{"./..."}},
+		},
+		{
+			name:    "reads go test flags passed through gotestsum",
+			command: "gotestsum --format dots -- -race ./pkg -args -test.short",
+			want:    TestFlags{Race: true, Packages: []string{"./pkg"}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		o.Spec(tt.name, func(expect expect.Expectation) {
+			flags, err := ParseTestCommand(tt.command)
+			expect(err).To(matchers.BeNil())
+			expect(flags).To(matchers.Equal(tt.want))
+		})
+	}
+
+	o.Spec("reports commands not running go test", func(expect expect.Expectation) {
+		_, err := ParseTestCommand("make test")
+		expect(err).To(matchers.Equal(ErrNotGoTest))
+	})
+
+	o.Spec("reports invalid flag values", func(expect expect.Expectation) {
+		_, err := ParseTestCommand("go test -count=many ./...")
+		expect(err).To(matchers.Not(matchers.BeNil()))
+		_, err = ParseTestCommand("go test ./... -timeout")
+		expect(err).To(matchers.Not(matchers.BeNil()))
+	})
+}
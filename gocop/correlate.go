@@ -0,0 +1,241 @@
+package gocop
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Dimension is a property of runs, such as their -race flag or Go version, failure rates are compared across
+type Dimension struct {
+	Name string
+	// Flag is the go test flag of a dimension which is either true or false, describing its values as with or
+	// without the flag
+	Flag string
+	// expr selects the value of the dimension of a run, empty when unknown
+	expr string
+}
+
+// Dimensions are the run configuration and environment failure rates of packages are correlated with
+var Dimensions = []Dimension{
+	{Name: "race", Flag: "-race", expr: "COALESCE(run.race, false)::text"},
+	{Name: "short", Flag: "-short", expr: "COALESCE(run.short, false)::text"},
+	{Name: "benchmark", Flag: "-bench", expr: "COALESCE(run.benchmark, false)::text"},
+	{Name: "tags", expr: "COALESCE(NULLIF(run.tags, ''), 'none')"},
+	{Name: "count", expr: "COALESCE(run.flags->>'count', '')"},
+	{Name: "cpu", expr: "COALESCE(run.flags->>'cpu', '')"},
+	{Name: MetaGoVersion, expr: "COALESCE(run.metadata->>'" + MetaGoVersion + "', '')"},
+	{Name: "platform", expr: "CONCAT_WS('/', run.metadata->>'" + MetaGOOS + "', run.metadata->>'" + MetaGOARCH + "')"},
+	{Name: MetaRunner, expr: "COALESCE(run.metadata->>'" + MetaRunner + "', '')"},
+	{Name: MetaGOMAXPROCS, expr: "COALESCE(run.metadata->>'" + MetaGOMAXPROCS + "', '')"},
+}
+
+// ConfigOutcome counts the runs of a package with a configuration, by the value of each dimension, and how many
+// of them failed
+type ConfigOutcome struct {
+	Package  string
+	Config   map[string]string
+	Runs     int
+	Failures int
+}
+
+// GetConfigOutcomes counts the runs of each package in runs matching a filter by configuration
+//
+// A package failed in a run when it failed or was flaky, while runs in which it was skipped are not counted.
+func GetConfigOutcomes(db *sql.DB, filter RunFilter) ([]ConfigOutcome, error) {
+	columns := make([]string, 0, len(Dimensions))
+	exprs := make([]string, 0, len(Dimensions))
+	for i, d := range Dimensions {
+		column := "d" + strconv.Itoa(i)
+		columns = append(columns, column)
+		exprs = append(exprs, d.expr+" AS "+column)
+	}
+	dims := strings.Join(columns, ", ")
+
+	where, args := filter.where("test.result<>'" + ResultSkip + "'")
+	sqlStr := `
+		SELECT package, ` + dims + `, COUNT(*), COUNT(*) FILTER (WHERE failed)
+		FROM (
+			SELECT test.package, ` + strings.Join(exprs, ", ") + `,
+				BOOL_OR(test.result IN ('` + ResultFail + `', '` + ResultFlaky + `')) AS failed
+			FROM test
+			JOIN run ON run.created = test.created
+			` + where + `
+			GROUP BY run.created, test.package, ` + dims + `
+		) runs
+		GROUP BY package, ` + dims + `
+		ORDER BY package
+	`
+
+	rows, err := db.Query(ReplaceSQL(sqlStr, "?"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	outcomes := make([]ConfigOutcome, 0)
+	for rows.Next() {
+		o := ConfigOutcome{Config: make(map[string]string)}
+		values := make([]string, len(Dimensions))
+		dest := []interface{}{&o.Package}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		dest = append(dest, &o.Runs, &o.Failures)
+
+		err = rows.Scan(dest...)
+		if err != nil {
+			return nil, err
+		}
+		for i, d := range Dimensions {
+			o.Config[d.Name] = values[i]
+		}
+		outcomes = append(outcomes, o)
+	}
+
+	return outcomes, rows.Err()
+}
+
+// Correlation is a configuration in which a package fails disproportionately often compared to the other
+// configurations it ran in
+type Correlation struct {
+	Package       string  `json:"package"`
+	Dimension     string  `json:"dimension"`
+	Value         string  `json:"value"`
+	Runs          int     `json:"runs"`
+	Failures      int     `json:"failures"`
+	Rate          float64 `json:"rate"`
+	OtherRuns     int     `json:"other_runs"`
+	OtherFailures int     `json:"other_failures"`
+	OtherRate     float64 `json:"other_rate"`
+}
+
+// Correlations lists configurations in which packages fail disproportionately often
+type Correlations []Correlation
+
+// Correlate finds the values of dimensions under which packages fail disproportionately often
+//
+// The runs of a package with a value are compared to its runs with any other known value of the dimension. The
+// value correlates with failures when the lower bound of its failure rate at a confidence is above the upper
+// bound of the failure rate of the other runs, each having at least minRuns runs.
+func Correlate(outcomes []ConfigOutcome, minRuns int, confidence float64) Correlations {
+	type key struct {
+		pkg       string
+		dimension int
+		value     string
+	}
+	type count struct {
+		runs, failures int
+	}
+
+	counts := make(map[key]*count)
+	totals := make(map[key]*count)
+	add := func(m map[key]*count, k key, o ConfigOutcome) {
+		c, ok := m[k]
+		if !ok {
+			c = &count{}
+			m[k] = c
+		}
+		c.runs += o.Runs
+		c.failures += o.Failures
+	}
+	for _, o := range outcomes {
+		for i, d := range Dimensions {
+			value := o.Config[d.Name]
+			if value == "" {
+				continue
+			}
+			add(counts, key{o.Package, i, value}, o)
+			add(totals, key{o.Package, i, ""}, o)
+		}
+	}
+
+	correlations := make(Correlations, 0)
+	for k, c := range counts {
+		total := totals[key{k.pkg, k.dimension, ""}]
+		otherRuns, otherFailures := total.runs-c.runs, total.failures-c.failures
+		if c.runs < minRuns || otherRuns < minRuns || otherRuns == 0 {
+			continue
+		}
+
+		low, _ := WilsonInterval(c.failures, c.runs, confidence)
+		_, otherHigh := WilsonInterval(otherFailures, otherRuns, confidence)
+		if low <= otherHigh {
+			continue
+		}
+
+		correlations = append(correlations, Correlation{
+			Package:       k.pkg,
+			Dimension:     Dimensions[k.dimension].Name,
+			Value:         k.value,
+			Runs:          c.runs,
+			Failures:      c.failures,
+			Rate:          float64(c.failures) / float64(c.runs),
+			OtherRuns:     otherRuns,
+			OtherFailures: otherFailures,
+			OtherRate:     float64(otherFailures) / float64(otherRuns),
+		})
+	}
+
+	sort.Slice(correlations, func(i, j int) bool {
+		a, b := correlations[i], correlations[j]
+		if a.Rate-a.OtherRate != b.Rate-b.OtherRate {
+			return a.Rate-a.OtherRate > b.Rate-b.OtherRate
+		}
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		if a.Dimension != b.Dimension {
+			return a.Dimension < b.Dimension
+		}
+		return a.Value < b.Value
+	})
+	return correlations
+}
+
+// Condition describes the configuration, such as under -race or with go_version=go1.12
+func (c Correlation) Condition() string {
+	for _, d := range Dimensions {
+		if d.Name != c.Dimension || d.Flag == "" {
+			continue
+		}
+		switch c.Value {
+		case "true":
+			return "under " + d.Flag
+		case "false":
+			return "without " + d.Flag
+		}
+	}
+	return "with " + c.Dimension + "=" + c.Value
+}
+
+// Text lists each package with the configuration it fails in compared to the others
+func (c Correlations) Text() string {
+	lines := make([]string, 0, len(c))
+	for _, corr := range c {
+		lines = append(lines, fmt.Sprintf("%s fails %.1f%% %s (%d of %d runs), %.1f%% otherwise (%d of %d runs)",
+			corr.Package, corr.Rate*100, corr.Condition(), corr.Failures, corr.Runs, corr.OtherRate*100, corr.OtherFailures, corr.OtherRuns))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Records lists the correlations with a header row
+func (c Correlations) Records() [][]string {
+	records := [][]string{{"package", "dimension", "value", "runs", "failures", "rate", "other_runs", "other_failures", "other_rate"}}
+	for _, corr := range c {
+		records = append(records, []string{
+			corr.Package,
+			corr.Dimension,
+			corr.Value,
+			strconv.Itoa(corr.Runs),
+			strconv.Itoa(corr.Failures),
+			strconv.FormatFloat(corr.Rate, 'f', 4, 64),
+			strconv.Itoa(corr.OtherRuns),
+			strconv.Itoa(corr.OtherFailures),
+			strconv.FormatFloat(corr.OtherRate, 'f', 4, 64),
+		})
+	}
+	return records
+}
@@ -0,0 +1,52 @@
+package gocop
+
+import (
+	"testing"
+
+	"github.com/poy/onpar"
+	"github.com/poy/onpar/expect"
+	"github.com/poy/onpar/matchers"
+)
+
+func TestCorrelate(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	config := func(race, goVersion string) map[string]string {
+		return map[string]string{"race": race, "short": "false", "benchmark": "false", "tags": "none", MetaGoVersion: goVersion}
+	}
+
+	o.Spec("finds configurations failing disproportionately often", func(expect expect.Expectation) {
+		correlations := Correlate([]ConfigOutcome{
+			{Package: "pkg", Config: config("true", "go1.12"), Runs: 20, Failures: 6},
+			{Package: "pkg", Config: config("true", "go1.13"), Runs: 20, Failures: 6},
+			{Package: "pkg", Config: config("false", "go1.12"), Runs: 30, Failures: 0},
+			{Package: "pkg", Config: config("false", "go1.13"), Runs: 30, Failures: 0},
+		}, 10, 0.95)
+
+		expect(correlations).To(matchers.HaveLen(1))
+		expect(correlations[0].Dimension).To(matchers.Equal("race"))
+		expect(correlations[0].Value).To(matchers.Equal("true"))
+		expect(correlations.Text()).To(matchers.Equal("pkg fails 30.0% under -race (12 of 40 runs), 0.0% otherwise (0 of 60 runs)"))
+		expect(correlations.Records()).To(matchers.HaveLen(2))
+	})
+
+	o.Spec("ignores unknown values and configurations with too few runs", func(expect expect.Expectation) {
+		correlations := Correlate([]ConfigOutcome{
+			{Package: "pkg", Config: config("false", "go1.12"), Runs: 50, Failures: 25},
+			{Package: "pkg", Config: config("false", ""), Runs: 50, Failures: 0},
+			{Package: "pkg", Config: config("true", "go1.13"), Runs: 5, Failures: 0},
+		}, 10, 0.95)
+
+		expect(correlations).To(matchers.HaveLen(0))
+	})
+
+	o.Spec("describes configurations", func(expect expect.Expectation) {
+		expect(Correlation{Dimension: "race", Value: "false"}.Condition()).To(matchers.Equal("without -race"))
+		expect(Correlation{Dimension: MetaGoVersion, Value: "go1.12"}.Condition()).To(matchers.Equal("with go_version=go1.12"))
+	})
+}
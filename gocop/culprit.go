@@ -0,0 +1,179 @@
+package gocop
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CommitResult is the outcome of a package in a run of a commit
+type CommitResult struct {
+	Created time.Time `json:"created"`
+	Sha     string    `json:"sha"`
+	Result  string    `json:"result"`
+}
+
+// Culprit is the range of commits in which a package started failing
+type Culprit struct {
+	Package string `json:"package"`
+	// LastPass is the latest run the package passed in before it started failing, nil when it failed
+	// in every run of the history
+	LastPass  *CommitResult `json:"last_pass,omitempty"`
+	FirstFail CommitResult  `json:"first_fail"`
+	// Failures counts the runs failing since FirstFail
+	Failures int `json:"failures"`
+	// Commits lists the commits of the range which changed the package or its dependencies, when looked up
+	Commits []string `json:"commits,omitempty"`
+}
+
+// ErrNotFailing reports a package which did not fail in its latest run
+var ErrNotFailing = errors.New("package is not failing")
+
+// FindCulprit finds the last passing and first failing run of a package from its history, most recent first
+//
+// Failures of runs in which the package was flaky, and runs in which it was skipped, are ignored.
+func FindCulprit(pkg string, history []CommitResult) (Culprit, error) {
+	culprit := Culprit{Package: pkg}
+	for i := range history {
+		c := history[i]
+		switch c.Result {
+		case ResultFail:
+			culprit.FirstFail = c
+			culprit.Failures++
+		case ResultPass:
+			if culprit.Failures == 0 {
+				return culprit, ErrNotFailing
+			}
+			culprit.LastPass = &c
+			return culprit, nil
+		}
+	}
+
+	if culprit.Failures == 0 {
+		return culprit, ErrNotFailing
+	}
+	return culprit, nil
+}
+
+// Range is the git revision range of commits after the last pass up to the first failure
+func (c Culprit) Range() string {
+	if c.LastPass == nil {
+		return c.FirstFail.Sha
+	}
+	return c.LastPass.Sha + ".." + c.FirstFail.Sha
+}
+
+// Text describes the commit range in which the package started failing
+func (c Culprit) Text() string {
+	if c.LastPass == nil {
+		return fmt.Sprintf("%s failed in all %d stored runs, first at %s on %s", c.Package, c.Failures,
+			c.FirstFail.Sha, c.FirstFail.Created.UTC().Format(time.RFC3339))
+	}
+
+	text := fmt.Sprintf("%s passed at %s on %s\n%s failed at %s on %s, failing in %d runs since\n%s",
+		c.Package, c.LastPass.Sha, c.LastPass.Created.UTC().Format(time.RFC3339),
+		c.Package, c.FirstFail.Sha, c.FirstFail.Created.UTC().Format(time.RFC3339), c.Failures, c.Range())
+	if c.LastPass.Sha == c.FirstFail.Sha {
+		text += "\nthe same commit passed and failed, so the failure may be flaky or caused by the environment"
+	}
+	if c.Commits != nil {
+		text += fmt.Sprintf("\n\n%d commits changed the package or its dependencies:", len(c.Commits))
+		for _, commit := range c.Commits {
+			text += "\n  " + commit
+		}
+	}
+	return text
+}
+
+// Records lists the last passing and first failing run with a header row
+func (c Culprit) Records() [][]string {
+	records := [][]string{{"package", "result", "sha", "created"}}
+	if c.LastPass != nil {
+		records = append(records, []string{c.Package, c.LastPass.Result, c.LastPass.Sha, c.LastPass.Created.UTC().Format(time.RFC3339)})
+	}
+	records = append(records, []string{c.Package, c.FirstFail.Result, c.FirstFail.Sha, c.FirstFail.Created.UTC().Format(time.RFC3339)})
+
+	return records
+}
+
+// DependencyPaths lists the directories of the packages a package depends on within the git work tree
+// of dir, relative to its root, along with the module files
+func DependencyPaths(dir, pkg string) ([]string, error) {
+	root, err := command(dir, "git", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	root = strings.TrimSpace(root)
+
+	out, err := command(dir, "go", "list", "-deps", "-test", "-f", "{{if not .Standard}}{{.Dir}}{{end}}", pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0)
+	for _, d := range strings.Split(out, "\n") {
+		if d == "" {
+			continue
+		}
+		rel, err := filepath.Rel(root, d)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		paths = appendNew(paths, filepath.ToSlash(rel))
+	}
+
+	module, err := command(dir, "go", "list", "-m", "-f", "{{.GoMod}}")
+	if err == nil && strings.TrimSpace(module) != "" {
+		rel, err := filepath.Rel(root, strings.TrimSpace(module))
+		if err == nil && !strings.HasPrefix(rel, "..") {
+			rel = filepath.ToSlash(rel)
+			paths = appendNew(paths, rel, strings.TrimSuffix(rel, ".mod")+".sum")
+		}
+	}
+
+	return paths, nil
+}
+
+// CommitLog lists the commits of a revision range which changed any of paths
+func CommitLog(dir, revisions string, paths []string) ([]string, error) {
+	args := []string{"log", "--no-merges", "--format=%h %an %ad %s", "--date=short", revisions}
+	if len(paths) > 0 {
+		args = append(args, "--")
+		for _, p := range paths {
+			// git pathspecs are relative to the working directory, the magic makes them relative to the root
+			args = append(args, ":(top)"+p)
+		}
+	}
+
+	out, err := command(dir, "git", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	commits := make([]string, 0)
+	for _, line := range strings.Split(out, "\n") {
+		if line != "" {
+			commits = append(commits, line)
+		}
+	}
+	return commits, nil
+}
+
+// command runs a command in dir, returning its output and including its error output in errors
+func command(dir, name string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %v: %s", name, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,75 @@
+package gocop
+
+import (
+	"testing"
+	"time"
+
+	"github.com/poy/onpar"
+	"github.com/poy/onpar/expect"
+	"github.com/poy/onpar/matchers"
+)
+
+func TestFindCulprit(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	at := func(hours int) time.Time {
+		return time.Date(2020, 1, 1, hours, 0, 0, 0, time.UTC)
+	}
+
+	o.Spec("finds the last pass before the failures", func(expect expect.Expectation) {
+		culprit, err := FindCulprit("example.com/broken", []CommitResult{
+			{Created: at(6), Sha: "f", Result: ResultFail},
+			{Created: at(5), Sha: "e", Result: ResultFlaky},
+			{Created: at(4), Sha: "d", Result: ResultFail},
+			{Created: at(3), Sha: "c", Result: ResultSkip},
+			{Created: at(2), Sha: "b", Result: ResultPass},
+			{Created: at(1), Sha: "a", Result: ResultFail},
+		})
+		expect(err).To(matchers.BeNil())
+		expect(culprit.LastPass.Sha).To(matchers.Equal("b"))
+		expect(culprit.FirstFail.Sha).To(matchers.Equal("d"))
+		expect(culprit.Failures).To(matchers.Equal(2))
+		expect(culprit.Range()).To(matchers.Equal("b..d"))
+		expect(culprit.Text()).To(matchers.Equal("example.com/broken passed at b on 2020-01-01T02:00:00Z\n" +
+			"example.com/broken failed at d on 2020-01-01T04:00:00Z, failing in 2 runs since\nb..d"))
+	})
+
+	o.Spec("reports packages failing in every run", func(expect expect.Expectation) {
+		culprit, err := FindCulprit("example.com/broken", []CommitResult{
+			{Created: at(2), Sha: "b", Result: ResultFail},
+			{Created: at(1), Sha: "a", Result: ResultFail},
+		})
+		expect(err).To(matchers.BeNil())
+		expect(culprit.LastPass).To(matchers.BeNil())
+		expect(culprit.FirstFail.Sha).To(matchers.Equal("a"))
+		expect(culprit.Records()).To(matchers.HaveLen(2))
+	})
+
+	o.Spec("ignores flaky failures of passing packages", func(expect expect.Expectation) {
+		_, err := FindCulprit("example.com/flaky", []CommitResult{
+			{Created: at(2), Sha: "b", Result: ResultFlaky},
+			{Created: at(1), Sha: "a", Result: ResultPass},
+		})
+		expect(err).To(matchers.Equal(ErrNotFailing))
+
+		_, err = FindCulprit("example.com/unknown", nil)
+		expect(err).To(matchers.Equal(ErrNotFailing))
+	})
+
+	o.Spec("lists commits with their suggestions", func(expect expect.Expectation) {
+		culprit := Culprit{
+			Package:   "example.com/broken",
+			LastPass:  &CommitResult{Created: at(1), Sha: "a", Result: ResultPass},
+			FirstFail: CommitResult{Created: at(2), Sha: "a", Result: ResultFail},
+			Failures:  1,
+			Commits:   []string{"0123abc dev 2020-01-01 change db"},
+		}
+		expect(culprit.Text()).To(matchers.ContainSubstring("may be flaky"))
+		expect(culprit.Text()).To(matchers.ContainSubstring("1 commits changed the package or its dependencies:\n  0123abc dev 2020-01-01 change db"))
+	})
+}
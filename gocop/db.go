@@ -2,9 +2,11 @@ package gocop
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
@@ -27,6 +29,45 @@ type TestRun struct {
 	Race      bool
 	Tags      []string
 	Duration  time.Duration
+	// Kind tells runs of CI builds, which make up the history of branches, from runs of other commands
+	Kind string
+	// Metadata describes the environment of the run, such as the MetaGoVersion it ran with
+	Metadata map[string]string
+	// Flags is the configuration parsed from Command
+	Flags TestFlags
+}
+
+const (
+	// RunKindCI marks a run of a CI build
+	RunKindCI = "ci"
+	// RunKindVerify marks a run of gocop verify checking a fix of a flaky test
+	RunKindVerify = "verify"
+)
+
+const (
+	// MetaGoVersion is the metadata key of the Go version a run was built with, such as go1.12.4
+	MetaGoVersion = "go_version"
+	// MetaGOOS is the metadata key of the operating system a run ran on
+	MetaGOOS = "goos"
+	// MetaGOARCH is the metadata key of the architecture a run ran on
+	MetaGOARCH = "goarch"
+	// MetaGOMAXPROCS is the metadata key of the GOMAXPROCS, or CPU count, a run ran with
+	MetaGOMAXPROCS = "gomaxprocs"
+	// MetaRunner is the metadata key of the CI runner, or runner labels, a run ran on
+	MetaRunner = "runner"
+)
+
+// RunFilter selects runs by their metadata and pages through the matches
+type RunFilter struct {
+	Repo   string
+	Branch string
+	Sha    string
+	Since  time.Time
+	Until  time.Time
+	// Kind selects runs of a kind, RunKindCI unless set
+	Kind   string
+	Limit  int
+	Offset int
 }
 
 // TestResult contains data about a test result
@@ -36,6 +77,8 @@ type TestResult struct {
 	Result   string
 	Duration time.Duration
 	Coverage float64
+	// Shuffle is the seed of the order tests ran in with -shuffle, or 0 without
+	Shuffle int64
 }
 
 // ConnectDB connects to the database
@@ -57,19 +100,40 @@ func ConnectDB(host, port, user, password, dbname, sslmode string) *sql.DB {
 // InsertRun inserts a new entry to the run table in the database
 func InsertRun(db *sql.DB, run TestRun) (sql.Result, error) {
 	sqlStr := `
-		INSERT INTO run (created, build_id, repo, duration, branch, sha, cmd, benchmark, short, race, tags)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		INSERT INTO run (created, build_id, repo, duration, branch, sha, cmd, benchmark, short, race, tags, kind, metadata, flags)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 	`
 
 	sort.Strings(run.Tags)
 	tags := strings.Join(run.Tags, " ")
+	kind := run.Kind
+	if kind == "" {
+		kind = RunKindCI
+	}
+	// runs without metadata store NULL rather than an empty object
+	var metadata interface{}
+	if len(run.Metadata) > 0 {
+		b, err := json.Marshal(run.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		metadata = string(b)
+	}
+	var flags interface{}
+	if !reflect.DeepEqual(run.Flags, TestFlags{}) {
+		b, err := json.Marshal(run.Flags)
+		if err != nil {
+			return nil, err
+		}
+		flags = string(b)
+	}
 
 	res, err := db.Exec(
 		sqlStr,
 		run.Created,
 		run.BuildID,
 		run.Repo,
-		run.Duration,
+		run.Duration/time.Millisecond,
 		run.Branch,
 		run.Sha,
 		run.Command,
@@ -77,11 +141,43 @@ func InsertRun(db *sql.DB, run TestRun) (sql.Result, error) {
 		run.Short,
 		run.Race,
 		tags,
+		kind,
+		metadata,
+		flags,
 	)
 
 	return res, err
 }
 
+// TestCaseResult contains data about the result of a single test within a package
+type TestCaseResult struct {
+	Created  time.Time
+	Package  string
+	Name     string
+	Result   string
+	Duration time.Duration
+	Output   []string
+}
+
+// MaxStoredOutputLines limits how much of the output of a failed test is stored
+const MaxStoredOutputLines = 100
+
+// FlakyCount reports how often a package, or a test within it, was flaky or failed across runs
+type FlakyCount struct {
+	Package string `json:"package"`
+	Test    string `json:"test,omitempty"`
+	Flaky   int    `json:"flaky"`
+	Failed  int    `json:"failed"`
+	Runs    int    `json:"runs"`
+}
+
+// CoveragePoint is the coverage percentage of a run
+type CoveragePoint struct {
+	Created  time.Time `json:"created"`
+	Sha      string    `json:"sha"`
+	Coverage float64   `json:"coverage"`
+}
+
 // GetRun retrieves information about a run
 func GetRun(db *sql.DB, buildID int64) *sql.Row {
 	sqlStr := `SELECT build_id, created, duration, cmd, repo, branch, sha, benchmark, race, short, tags
@@ -93,12 +189,12 @@ func GetRun(db *sql.DB, buildID int64) *sql.Row {
 
 // InsertTests adds test results to database
 func InsertTests(db *sql.DB, created time.Time, testResults []TestResult) (sql.Result, error) {
-	sqlStr := "INSERT INTO test(created, package, result, duration, coverage) VALUES "
+	sqlStr := "INSERT INTO test(created, package, result, duration, coverage, shuffle) VALUES "
 	vals := []interface{}{}
 
 	for _, row := range testResults {
-		sqlStr += "(?, ?, ?, ?, ?),"
-		vals = append(vals, row.Created, row.Package, row.Result, row.Duration/time.Millisecond, row.Coverage)
+		sqlStr += "(?, ?, ?, ?, ?, NULLIF(?, 0)),"
+		vals = append(vals, row.Created, row.Package, row.Result, row.Duration/time.Millisecond, row.Coverage, row.Shuffle)
 	}
 	if len(vals) == 0 {
 		return nil, errors.New("no test results found")
@@ -117,6 +213,91 @@ func InsertTests(db *sql.DB, created time.Time, testResults []TestResult) (sql.R
 	return stmt.Exec(vals...)
 }
 
+// InsertTestCases adds the results of individual tests to database
+func InsertTestCases(db *sql.DB, created time.Time, results []TestCaseResult) (sql.Result, error) {
+	sqlStr := "INSERT INTO testcase(created, package, name, result, duration, output) VALUES "
+	vals := []interface{}{}
+
+	for _, row := range results {
+		output := row.Output
+		if len(output) > MaxStoredOutputLines {
+			output = output[:MaxStoredOutputLines]
+		}
+
+		sqlStr += "(?, ?, ?, ?, ?, ?),"
+		vals = append(vals, created, row.Package, row.Name, row.Result, row.Duration/time.Millisecond, strings.Join(output, "\n"))
+	}
+	if len(vals) == 0 {
+		return nil, errors.New("no test case results found")
+	}
+
+	sqlStr = ReplaceSQL(strings.TrimSuffix(sqlStr, ","), "?")
+	stmt, err := db.Prepare(sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return stmt.Exec(vals...)
+}
+
+// StoreRun inserts a run with the results of its packages, and of their failed and flaky tests
+func StoreRun(db *sql.DB, run TestRun, results PackageResults, flaky FlakyPackages) error {
+	testResults, testCases := runRecords(run.Created, results, flaky)
+
+	_, err := InsertRun(db, run)
+	if err != nil {
+		return err
+	}
+
+	_, err = InsertTests(db, run.Created, testResults)
+	if err != nil {
+		return err
+	}
+
+	// only failed and flaky tests are stored individually
+	if len(testCases) > 0 {
+		_, err = InsertTestCases(db, run.Created, testCases)
+	}
+	return err
+}
+
+// runRecords converts the parsed results of a run and its retests into rows for storage
+func runRecords(created time.Time, results PackageResults, flaky FlakyPackages) ([]TestResult, []TestCaseResult) {
+	testResults := make([]TestResult, 0)
+	testCases := make([]TestCaseResult, 0)
+
+	for _, result := range results {
+		testResults = append(testResults, result.TestResult(created))
+		for _, test := range result.Tests {
+			if test.Outcome == ResultFail {
+				testCases = append(testCases, TestCaseResult{
+					Created:  created,
+					Package:  result.Package,
+					Name:     test.Name,
+					Result:   ResultFail,
+					Duration: time.Duration(test.Duration * float64(time.Second)),
+					Output:   test.Output,
+				})
+			}
+		}
+	}
+
+	for _, pkg := range flaky {
+		testResults = append(testResults, TestResult{Package: pkg.Package, Result: ResultFlaky, Created: created})
+		for _, test := range pkg.Tests {
+			testCases = append(testCases, TestCaseResult{
+				Created: created,
+				Package: pkg.Package,
+				Name:    test.Name,
+				Result:  ResultFlaky,
+				Output:  testOutput(pkg.Runs, test.Name),
+			})
+		}
+	}
+
+	return testResults, testCases
+}
+
 // GetTests retrieves test results for a build
 func GetTests(db *sql.DB, created time.Time) (*sql.Rows, error) {
 	sqlStr := `
@@ -136,3 +317,778 @@ func ReplaceSQL(old, searchPattern string) string {
 	}
 	return old
 }
+
+// GetPreviousResults retrieves the test results of the latest run on a branch before a time
+func GetPreviousResults(db *sql.DB, repo, branch string, before time.Time) ([]TestResult, error) {
+	sqlStr := `
+		SELECT created, package, result, duration, coverage, shuffle
+		FROM test
+		WHERE created = (
+			SELECT MAX(created)
+			FROM run
+			WHERE repo=$1 AND branch=$2 AND created < $3 AND kind='ci'
+		)
+	`
+
+	rows, err := db.Query(sqlStr, repo, branch, before)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanTestResults(rows)
+}
+
+// GetFlakyPackages lists packages found flaky in runs of a repository since a time
+func GetFlakyPackages(db *sql.DB, repo string, since time.Time) ([]string, error) {
+	sqlStr := `
+		SELECT DISTINCT test.package
+		FROM test
+		JOIN run ON run.created = test.created
+		WHERE run.repo=$1 AND run.kind='ci' AND test.created >= $2 AND test.result=$3
+		ORDER BY test.package
+	`
+
+	rows, err := db.Query(sqlStr, repo, since, ResultFlaky)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pkgs := make([]string, 0)
+	for rows.Next() {
+		var pkg string
+		err = rows.Scan(&pkg)
+		if err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, pkg)
+	}
+
+	return pkgs, rows.Err()
+}
+
+// scanTestResults reads rows of created, package, result, duration, coverage and shuffle into test results
+func scanTestResults(rows *sql.Rows) ([]TestResult, error) {
+	defer rows.Close()
+
+	results := make([]TestResult, 0)
+	for rows.Next() {
+		var result TestResult
+		var duration sql.NullInt64
+		var coverage sql.NullFloat64
+		var shuffle sql.NullInt64
+		err := rows.Scan(&result.Created, &result.Package, &result.Result, &duration, &coverage, &shuffle)
+		if err != nil {
+			return nil, err
+		}
+
+		// durations are stored in milliseconds
+		result.Duration = time.Duration(duration.Int64) * time.Millisecond
+		result.Coverage = coverage.Float64
+		result.Shuffle = shuffle.Int64
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+// FindRuns lists runs matching a filter, most recent first
+func FindRuns(db *sql.DB, filter RunFilter) ([]TestRun, error) {
+	where, args := filter.where()
+	sqlStr := `
+		SELECT created, build_id, repo, branch, sha, cmd, benchmark, short, race, tags, duration, kind, metadata, flags
+		FROM run
+		` + where + `
+		ORDER BY created DESC
+	` + filter.page(&args)
+
+	rows, err := db.Query(ReplaceSQL(sqlStr, "?"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := make([]TestRun, 0)
+	for rows.Next() {
+		run, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
+
+// FindRun retrieves the run created at a time, returning sql.ErrNoRows when there is none
+func FindRun(db *sql.DB, created time.Time) (TestRun, error) {
+	sqlStr := `
+		SELECT created, build_id, repo, branch, sha, cmd, benchmark, short, race, tags, duration, kind, metadata, flags
+		FROM run
+		WHERE created=$1
+	`
+
+	return scanRun(db.QueryRow(sqlStr, created))
+}
+
+// GetResults retrieves the package results of a run
+func GetResults(db *sql.DB, created time.Time) ([]TestResult, error) {
+	sqlStr := `
+		SELECT created, package, result, duration, coverage, shuffle
+		FROM test
+		WHERE created=$1
+		ORDER BY package, result
+	`
+
+	rows, err := db.Query(sqlStr, created)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanTestResults(rows)
+}
+
+// GetPackageHistory retrieves the results of a package in runs matching a filter, most recent first
+func GetPackageHistory(db *sql.DB, filter RunFilter, pkg string) ([]TestResult, error) {
+	where, args := filter.where("test.package=?")
+	args = append([]interface{}{pkg}, args...)
+	sqlStr := `
+		SELECT test.created, test.package, test.result, test.duration, test.coverage, test.shuffle
+		FROM test
+		JOIN run ON run.created = test.created
+		` + where + `
+		ORDER BY test.created DESC, test.result
+	` + filter.page(&args)
+
+	rows, err := db.Query(ReplaceSQL(sqlStr, "?"), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanTestResults(rows)
+}
+
+// GetFlakiestPackages ranks packages by how often they were flaky in runs matching a filter
+func GetFlakiestPackages(db *sql.DB, filter RunFilter) ([]FlakyCount, error) {
+	where, args := filter.where()
+	sqlStr := `
+		SELECT test.package, '',
+			COUNT(*) FILTER (WHERE test.result='flaky'),
+			COUNT(*) FILTER (WHERE test.result='fail'),
+			COUNT(DISTINCT test.created)
+		FROM test
+		JOIN run ON run.created = test.created
+		` + where + `
+		GROUP BY test.package
+		HAVING COUNT(*) FILTER (WHERE test.result='flaky') > 0
+		ORDER BY 3 DESC, 4 DESC, 1
+	` + filter.page(&args)
+
+	return queryFlakyCounts(db, sqlStr, args)
+}
+
+// GetFlakiestTests ranks tests by how often they were flaky in runs matching a filter
+//
+// Only failed and flaky test cases are stored, so the runs of a test are counted as the runs of its package.
+func GetFlakiestTests(db *sql.DB, filter RunFilter) ([]FlakyCount, error) {
+	where, args := filter.where()
+	runsWhere, runsArgs := filter.where()
+	args = append(args, runsArgs...)
+	sqlStr := `
+		SELECT tests.package, tests.name, tests.flaky, tests.failed, runs.runs
+		FROM (
+			SELECT testcase.package, testcase.name,
+				COUNT(*) FILTER (WHERE testcase.result='flaky') AS flaky,
+				COUNT(*) FILTER (WHERE testcase.result='fail') AS failed
+			FROM testcase
+			JOIN run ON run.created = testcase.created
+			` + where + `
+			GROUP BY testcase.package, testcase.name
+			HAVING COUNT(*) FILTER (WHERE testcase.result='flaky') > 0
+		) tests
+		JOIN (
+			SELECT test.package, COUNT(DISTINCT test.created) AS runs
+			FROM test
+			JOIN run ON run.created = test.created
+			` + runsWhere + `
+			GROUP BY test.package
+		) runs ON runs.package = tests.package
+		ORDER BY 3 DESC, 4 DESC, 1, 2
+	` + filter.page(&args)
+
+	return queryFlakyCounts(db, sqlStr, args)
+}
+
+// GetCoverageTrend retrieves the coverage of a package, or the average of all packages, in runs matching a filter
+func GetCoverageTrend(db *sql.DB, filter RunFilter, pkg string) ([]CoveragePoint, error) {
+	conditions := []string{"test.result='pass'", "test.coverage IS NOT NULL"}
+	if pkg != "" {
+		conditions = append(conditions, "test.package=?")
+	}
+	where, args := filter.where(conditions...)
+	if pkg != "" {
+		args = append([]interface{}{pkg}, args...)
+	}
+	sqlStr := `
+		SELECT run.created, COALESCE(run.sha, ''), AVG(test.coverage)
+		FROM test
+		JOIN run ON run.created = test.created
+		` + where + `
+		GROUP BY run.created, run.sha
+		ORDER BY run.created DESC
+	` + filter.page(&args)
+
+	rows, err := db.Query(ReplaceSQL(sqlStr, "?"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := make([]CoveragePoint, 0)
+	for rows.Next() {
+		var point CoveragePoint
+		err = rows.Scan(&point.Created, &point.Sha, &point.Coverage)
+		if err != nil {
+			return nil, err
+		}
+
+		// stored coverage is a fraction rather than a percentage
+		point.Coverage *= 100
+		points = append(points, point)
+	}
+
+	return points, rows.Err()
+}
+
+// where builds a WHERE clause with ? placeholders from conditions followed by the filter on the run table
+func (f RunFilter) where(conditions ...string) (string, []interface{}) {
+	args := make([]interface{}, 0)
+	add := func(condition string, arg interface{}) {
+		conditions = append(conditions, condition)
+		args = append(args, arg)
+	}
+	if f.Repo != "" {
+		add("run.repo=?", f.Repo)
+	}
+	if f.Branch != "" {
+		add("run.branch=?", f.Branch)
+	}
+	if f.Sha != "" {
+		add("run.sha=?", f.Sha)
+	}
+	if !f.Since.IsZero() {
+		add("run.created>=?", f.Since)
+	}
+	if !f.Until.IsZero() {
+		add("run.created<?", f.Until)
+	}
+	if f.Kind != "" {
+		add("run.kind=?", f.Kind)
+	} else {
+		add("run.kind=?", RunKindCI)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// page builds the LIMIT and OFFSET clause of a filter, appending its arguments
+func (f RunFilter) page(args *[]interface{}) string {
+	sqlStr := ""
+	if f.Limit > 0 {
+		sqlStr += " LIMIT ?"
+		*args = append(*args, f.Limit)
+	}
+	if f.Offset > 0 {
+		sqlStr += " OFFSET ?"
+		*args = append(*args, f.Offset)
+	}
+	return sqlStr
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRun(row scanner) (TestRun, error) {
+	var run TestRun
+	var buildID, duration sql.NullInt64
+	var repo, branch, sha, cmd, tags sql.NullString
+	var benchmark, short, race sql.NullBool
+	var metadata, flags []byte
+	err := row.Scan(&run.Created, &buildID, &repo, &branch, &sha, &cmd, &benchmark, &short, &race, &tags, &duration, &run.Kind, &metadata, &flags)
+	if err != nil {
+		return run, err
+	}
+	if len(metadata) > 0 {
+		err = json.Unmarshal(metadata, &run.Metadata)
+		if err != nil {
+			return run, err
+		}
+	}
+	if len(flags) > 0 {
+		err = json.Unmarshal(flags, &run.Flags)
+		if err != nil {
+			return run, err
+		}
+	}
+
+	run.BuildID = buildID.Int64
+	run.Repo = repo.String
+	run.Branch = branch.String
+	run.Sha = sha.String
+	run.Command = cmd.String
+	run.Benchmark = benchmark.Bool
+	run.Short = short.Bool
+	run.Race = race.Bool
+	run.Tags = strings.Fields(tags.String)
+	run.Duration = time.Duration(duration.Int64) * time.Millisecond
+
+	return run, nil
+}
+
+func queryFlakyCounts(db *sql.DB, sqlStr string, args []interface{}) ([]FlakyCount, error) {
+	rows, err := db.Query(ReplaceSQL(sqlStr, "?"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make([]FlakyCount, 0)
+	for rows.Next() {
+		var count FlakyCount
+		err = rows.Scan(&count.Package, &count.Test, &count.Flaky, &count.Failed, &count.Runs)
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
+
+// Quarantine marks a package, or a single test within it, as known to be flaky in a repository
+type Quarantine struct {
+	Repo    string    `json:"repo"`
+	Package string    `json:"package"`
+	Test    string    `json:"test"`
+	Reason  string    `json:"reason"`
+	Issue   string    `json:"issue,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+// GetQuarantined lists the quarantine entries of a repository, or of every repository when repo is empty
+func GetQuarantined(db *sql.DB, repo string) ([]Quarantine, error) {
+	sqlStr := `
+		SELECT repo, package, test, COALESCE(reason, ''), COALESCE(issue, ''), created
+		FROM quarantine
+		WHERE ($1 = '' OR repo = $1) AND resolved IS NULL
+		ORDER BY repo, package, test
+	`
+
+	rows, err := db.Query(sqlStr, repo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]Quarantine, 0)
+	for rows.Next() {
+		var q Quarantine
+		err = rows.Scan(&q.Repo, &q.Package, &q.Test, &q.Reason, &q.Issue, &q.Created)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, q)
+	}
+
+	return entries, rows.Err()
+}
+
+// InsertQuarantine quarantines a package or test, updating the reason of an existing entry and quarantining a
+// resolved one again
+//
+// Without an issue, the entry references the open issue filed for the package or test, if any.
+func InsertQuarantine(db *sql.DB, q Quarantine) (Quarantine, error) {
+	sqlStr := `
+		INSERT INTO quarantine (repo, package, test, reason, issue)
+		VALUES ($1, $2, $3, $4, COALESCE(NULLIF($5, ''), (
+			SELECT url FROM issue
+			WHERE repo=$1 AND package=$2 AND test=$3 AND state='open'
+			ORDER BY updated DESC LIMIT 1
+		)))
+		ON CONFLICT (repo, package, test) DO UPDATE
+		SET reason = EXCLUDED.reason,
+			issue = COALESCE(EXCLUDED.issue, quarantine.issue),
+			created = CASE WHEN quarantine.resolved IS NULL THEN quarantine.created ELSE EXCLUDED.created END,
+			resolved = NULL
+		RETURNING created, COALESCE(issue, '')
+	`
+
+	err := db.QueryRow(sqlStr, q.Repo, q.Package, q.Test, q.Reason, q.Issue).Scan(&q.Created, &q.Issue)
+	return q, err
+}
+
+// DeleteQuarantine removes a quarantine entry, reporting whether it existed
+func DeleteQuarantine(db *sql.DB, repo, pkg, test string) (bool, error) {
+	sqlStr := `DELETE FROM quarantine WHERE repo=$1 AND package=$2 AND test=$3`
+
+	res, err := db.Exec(sqlStr, repo, pkg, test)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ResolveQuarantine lifts the quarantine of a package or test verified as fixed by the run created at resolved,
+// reporting whether it was quarantined
+func ResolveQuarantine(db *sql.DB, repo, pkg, test string, resolved time.Time) (bool, error) {
+	sqlStr := `UPDATE quarantine SET resolved=$4 WHERE repo=$1 AND package=$2 AND test=$3 AND resolved IS NULL`
+
+	res, err := db.Exec(sqlStr, repo, pkg, test, resolved)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RunCount is the number of runs of a branch
+type RunCount struct {
+	Repo   string
+	Branch string
+	Runs   int
+}
+
+// PackageHealth totals the results of a package on a branch
+type PackageHealth struct {
+	Repo     string
+	Branch   string
+	Package  string
+	Passed   int
+	Failed   int
+	Flaky    int
+	Duration time.Duration
+	Coverage float64
+	Latest   string
+}
+
+// GetRunCounts counts the runs of each branch since a time
+func GetRunCounts(db *sql.DB, since time.Time) ([]RunCount, error) {
+	sqlStr := `
+		SELECT COALESCE(repo, ''), COALESCE(branch, ''), COUNT(*)
+		FROM run
+		WHERE created >= $1 AND kind='ci'
+		GROUP BY 1, 2
+		ORDER BY 1, 2
+	`
+
+	rows, err := db.Query(sqlStr, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make([]RunCount, 0)
+	for rows.Next() {
+		var count RunCount
+		err = rows.Scan(&count.Repo, &count.Branch, &count.Runs)
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
+
+// GetPackageHealth totals the results of each package on each branch since a time
+func GetPackageHealth(db *sql.DB, since time.Time) ([]PackageHealth, error) {
+	sqlStr := `
+		SELECT COALESCE(run.repo, ''), COALESCE(run.branch, ''), test.package,
+			COUNT(*) FILTER (WHERE test.result='pass'),
+			COUNT(*) FILTER (WHERE test.result='fail'),
+			COUNT(*) FILTER (WHERE test.result='flaky'),
+			COALESCE(AVG(test.duration) FILTER (WHERE test.result IN ('pass', 'fail')), 0),
+			COALESCE(AVG(test.coverage) FILTER (WHERE test.result='pass'), 0),
+			COALESCE((ARRAY_AGG(test.result ORDER BY test.created DESC) FILTER (WHERE test.result <> 'flaky'))[1], '')
+		FROM test
+		JOIN run ON run.created = test.created
+		WHERE test.created >= $1 AND run.kind='ci'
+		GROUP BY 1, 2, 3
+		ORDER BY 1, 2, 3
+	`
+
+	rows, err := db.Query(sqlStr, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	health := make([]PackageHealth, 0)
+	for rows.Next() {
+		var h PackageHealth
+		var duration float64
+		err = rows.Scan(&h.Repo, &h.Branch, &h.Package, &h.Passed, &h.Failed, &h.Flaky, &duration, &h.Coverage, &h.Latest)
+		if err != nil {
+			return nil, err
+		}
+
+		// durations are stored in milliseconds
+		h.Duration = time.Duration(duration * float64(time.Millisecond))
+		health = append(health, h)
+	}
+
+	return health, rows.Err()
+}
+
+// GetAnnouncements lists the failing and flaky notifications already sent for a branch
+func GetAnnouncements(db *sql.DB, repo, branch string) ([]Announcement, error) {
+	sqlStr := `
+		SELECT package, kind, created
+		FROM announcement
+		WHERE repo=$1 AND branch=$2
+	`
+
+	rows, err := db.Query(sqlStr, repo, branch)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	announced := make([]Announcement, 0)
+	for rows.Next() {
+		var a Announcement
+		err = rows.Scan(&a.Package, &a.Kind, &a.Created)
+		if err != nil {
+			return nil, err
+		}
+		announced = append(announced, a)
+	}
+
+	return announced, rows.Err()
+}
+
+// SaveAnnouncements records sent notifications, forgetting failures once they recover
+func SaveAnnouncements(db *sql.DB, notifications []Notification) error {
+	for _, n := range notifications {
+		var err error
+		if n.Kind == NotifyRecovered {
+			_, err = db.Exec(`DELETE FROM announcement WHERE repo=$1 AND branch=$2 AND package=$3 AND kind=$4`,
+				n.Repo, n.Branch, n.Package, NotifyFailing)
+		} else {
+			_, err = db.Exec(`
+				INSERT INTO announcement (repo, branch, package, kind, created)
+				VALUES ($1, $2, $3, $4, $5)
+				ON CONFLICT (repo, branch, package, kind) DO UPDATE SET created = EXCLUDED.created
+			`, n.Repo, n.Branch, n.Package, n.Kind, n.Created)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FlakyOccurrence totals the flaky runs of a package, or a single test within it, in a repository
+type FlakyOccurrence struct {
+	Package   string
+	Test      string
+	Runs      int
+	FirstSeen time.Time
+	LastSeen  time.Time
+	FirstSha  string
+	LastSha   string
+	Output    []string
+}
+
+// TrackedIssue is an issue filed for a flaky package or test
+type TrackedIssue struct {
+	Repo        string
+	Package     string
+	Test        string
+	Tracker     string
+	Key         string
+	URL         string
+	State       string
+	Occurrences int
+	LastSeen    time.Time
+	Created     time.Time
+	Updated     time.Time
+}
+
+// GetFlakyOccurrences totals the flaky runs of each test, or of packages without an identified test, since a time
+func GetFlakyOccurrences(db *sql.DB, repo string, since time.Time) ([]FlakyOccurrence, error) {
+	sqlStr := `
+		SELECT testcase.package, testcase.name, COUNT(DISTINCT testcase.created),
+			MIN(testcase.created), MAX(testcase.created),
+			(ARRAY_AGG(COALESCE(run.sha, '') ORDER BY testcase.created))[1],
+			(ARRAY_AGG(COALESCE(run.sha, '') ORDER BY testcase.created DESC))[1],
+			COALESCE((ARRAY_AGG(testcase.output ORDER BY testcase.created DESC) FILTER (WHERE testcase.output <> ''))[1], '')
+		FROM testcase
+		JOIN run ON run.created = testcase.created
+		WHERE run.repo=$1 AND run.kind='ci' AND testcase.created >= $2 AND testcase.result='flaky'
+		GROUP BY 1, 2
+		UNION ALL
+		SELECT test.package, '', COUNT(DISTINCT test.created),
+			MIN(test.created), MAX(test.created),
+			(ARRAY_AGG(COALESCE(run.sha, '') ORDER BY test.created))[1],
+			(ARRAY_AGG(COALESCE(run.sha, '') ORDER BY test.created DESC))[1],
+			''
+		FROM test
+		JOIN run ON run.created = test.created
+		WHERE run.repo=$1 AND run.kind='ci' AND test.created >= $2 AND test.result='flaky' AND NOT EXISTS (
+			SELECT 1 FROM testcase
+			WHERE testcase.created = test.created AND testcase.package = test.package AND testcase.result='flaky'
+		)
+		GROUP BY 1
+		ORDER BY 1, 2
+	`
+
+	rows, err := db.Query(sqlStr, repo, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	occurrences := make([]FlakyOccurrence, 0)
+	for rows.Next() {
+		var o FlakyOccurrence
+		var output string
+		err = rows.Scan(&o.Package, &o.Test, &o.Runs, &o.FirstSeen, &o.LastSeen, &o.FirstSha, &o.LastSha, &output)
+		if err != nil {
+			return nil, err
+		}
+		if output != "" {
+			o.Output = strings.Split(output, "\n")
+		}
+		occurrences = append(occurrences, o)
+	}
+
+	return occurrences, rows.Err()
+}
+
+// GetIssues lists the issues filed for a repository in a tracker
+func GetIssues(db *sql.DB, repo, tracker string) ([]TrackedIssue, error) {
+	sqlStr := `
+		SELECT repo, package, test, tracker, key, COALESCE(url, ''), state, occurrences, last_seen, created, updated
+		FROM issue
+		WHERE repo=$1 AND tracker=$2
+		ORDER BY package, test
+	`
+
+	rows, err := db.Query(sqlStr, repo, tracker)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	issues := make([]TrackedIssue, 0)
+	for rows.Next() {
+		var i TrackedIssue
+		err = rows.Scan(&i.Repo, &i.Package, &i.Test, &i.Tracker, &i.Key, &i.URL, &i.State, &i.Occurrences, &i.LastSeen, &i.Created, &i.Updated)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, i)
+	}
+
+	return issues, rows.Err()
+}
+
+// SaveIssue records an issue filed for a flaky package or test, and references it from its quarantine entry
+func SaveIssue(db *sql.DB, issue TrackedIssue) error {
+	sqlStr := `
+		INSERT INTO issue (repo, package, test, tracker, key, url, state, occurrences, last_seen, created, updated)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (repo, package, test, tracker) DO UPDATE
+		SET key = EXCLUDED.key, url = EXCLUDED.url, state = EXCLUDED.state, occurrences = EXCLUDED.occurrences,
+			last_seen = EXCLUDED.last_seen, created = EXCLUDED.created, updated = EXCLUDED.updated
+	`
+
+	_, err := db.Exec(sqlStr, issue.Repo, issue.Package, issue.Test, issue.Tracker, issue.Key, issue.URL,
+		issue.State, issue.Occurrences, issue.LastSeen, issue.Created, issue.Updated)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`UPDATE quarantine SET issue=$4 WHERE repo=$1 AND package=$2 AND test=$3`,
+		issue.Repo, issue.Package, issue.Test, issue.URL)
+	return err
+}
+
+// CountPassesSince counts the runs of a repository in which a package passed after a time
+func CountPassesSince(db *sql.DB, repo, pkg string, since time.Time) (int, error) {
+	sqlStr := `
+		SELECT COUNT(DISTINCT test.created)
+		FROM test
+		JOIN run ON run.created = test.created
+		WHERE run.repo=$1 AND run.kind='ci' AND test.package=$2 AND test.created > $3 AND test.result='pass'
+	`
+
+	var count int
+	err := db.QueryRow(sqlStr, repo, pkg, since).Scan(&count)
+	return count, err
+}
+
+// GetCommitHistory retrieves the outcome of a package in the latest runs of a branch, most recent first
+//
+// A run in which the package was also classified as flaky reports ResultFlaky.
+func GetCommitHistory(db *sql.DB, repo, branch, pkg string, limit int) ([]CommitResult, error) {
+	sqlStr := `
+		SELECT run.created, COALESCE(run.sha, ''),
+			CASE
+				WHEN BOOL_OR(test.result='flaky') THEN 'flaky'
+				WHEN BOOL_OR(test.result='fail') THEN 'fail'
+				ELSE MIN(test.result)
+			END
+		FROM test
+		JOIN run ON run.created = test.created
+		WHERE run.repo=$1 AND run.branch=$2 AND run.kind='ci' AND test.package=$3
+		GROUP BY run.created, run.sha
+		ORDER BY run.created DESC
+		LIMIT $4
+	`
+
+	rows, err := db.Query(sqlStr, repo, branch, pkg, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make([]CommitResult, 0)
+	for rows.Next() {
+		var c CommitResult
+		err = rows.Scan(&c.Created, &c.Sha, &c.Result)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, c)
+	}
+
+	return history, rows.Err()
+}
+
+// GetFailingShuffle retrieves the -shuffle seed of the latest failed run of a package on a branch, returning
+// sql.ErrNoRows when no failed run recorded a seed
+func GetFailingShuffle(db *sql.DB, repo, branch, pkg string) (int64, error) {
+	sqlStr := `
+		SELECT test.shuffle
+		FROM test
+		JOIN run ON run.created = test.created
+		WHERE run.repo=$1 AND run.branch=$2 AND run.kind='ci' AND test.package=$3
+			AND test.result=$4 AND test.shuffle IS NOT NULL
+		ORDER BY test.created DESC
+		LIMIT 1
+	`
+
+	var seed int64
+	err := db.QueryRow(sqlStr, repo, branch, pkg, ResultFail).Scan(&seed)
+	return seed, err
+}
@@ -0,0 +1,221 @@
+package gocop
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MetaCPUs is the metadata key of the number of CPUs of the machine a run ran on
+	MetaCPUs = "cpus"
+	// MetaHostname is the metadata key of the host a run ran on
+	MetaHostname = "hostname"
+	// MetaRunnerLabels is the metadata key of the comma-separated labels or tags of the CI runner
+	MetaRunnerLabels = "runner_labels"
+	// MetaCI is the metadata key of the CI system a run ran in, such as github-actions
+	MetaCI = "ci"
+	// MetaBuildURL is the metadata key of the URL of the CI build of a run
+	MetaBuildURL = "build_url"
+)
+
+// Environment describes where a run ran, detected from the git working tree and CI environment variables
+type Environment struct {
+	Repo     string
+	Branch   string
+	Sha      string
+	BuildID  int64
+	Metadata map[string]string
+}
+
+// ciSystem detects a CI system from its environment variables, filling what it knows of the environment
+type ciSystem struct {
+	name   string
+	detect func(env map[string]string) bool
+	fill   func(env map[string]string, e *Environment)
+}
+
+var ciSystems = []ciSystem{
+	{
+		name:   "github-actions",
+		detect: func(env map[string]string) bool { return env["GITHUB_ACTIONS"] == "true" },
+		fill: func(env map[string]string, e *Environment) {
+			e.Repo = env["GITHUB_REPOSITORY"]
+			// pull requests build a merge ref, while GITHUB_HEAD_REF is the branch they come from
+			e.Branch = first(env["GITHUB_HEAD_REF"], env["GITHUB_REF_NAME"], strings.TrimPrefix(env["GITHUB_REF"], "refs/heads/"))
+			e.Sha = env["GITHUB_SHA"]
+			e.BuildID, _ = strconv.ParseInt(env["GITHUB_RUN_ID"], 10, 64)
+			e.Metadata[MetaRunner] = env["RUNNER_NAME"]
+			if env["GITHUB_RUN_ID"] != "" {
+				e.Metadata[MetaBuildURL] = first(env["GITHUB_SERVER_URL"], "https://github.com") + "/" + env["GITHUB_REPOSITORY"] + "/actions/runs/" + env["GITHUB_RUN_ID"]
+			}
+		},
+	},
+	{
+		name:   "gitlab",
+		detect: func(env map[string]string) bool { return env["GITLAB_CI"] == "true" },
+		fill: func(env map[string]string, e *Environment) {
+			e.Repo = env["CI_PROJECT_PATH"]
+			e.Branch = first(env["CI_MERGE_REQUEST_SOURCE_BRANCH_NAME"], env["CI_COMMIT_BRANCH"], env["CI_COMMIT_REF_NAME"])
+			e.Sha = env["CI_COMMIT_SHA"]
+			e.BuildID, _ = strconv.ParseInt(env["CI_PIPELINE_ID"], 10, 64)
+			e.Metadata[MetaRunner] = env["CI_RUNNER_DESCRIPTION"]
+			e.Metadata[MetaRunnerLabels] = gitlabTags(env["CI_RUNNER_TAGS"])
+			e.Metadata[MetaBuildURL] = env["CI_JOB_URL"]
+		},
+	},
+	{
+		name:   "jenkins",
+		detect: func(env map[string]string) bool { return env["JENKINS_URL"] != "" },
+		fill: func(env map[string]string, e *Environment) {
+			e.Repo = RepoName(env["GIT_URL"])
+			e.Branch = first(env["CHANGE_BRANCH"], env["BRANCH_NAME"], strings.TrimPrefix(env["GIT_BRANCH"], "origin/"))
+			e.Sha = env["GIT_COMMIT"]
+			e.BuildID, _ = strconv.ParseInt(env["BUILD_NUMBER"], 10, 64)
+			e.Metadata[MetaRunner] = env["NODE_NAME"]
+			e.Metadata[MetaRunnerLabels] = strings.Join(strings.Fields(env["NODE_LABELS"]), ",")
+			e.Metadata[MetaBuildURL] = env["BUILD_URL"]
+		},
+	},
+	{
+		name:   "buildkite",
+		detect: func(env map[string]string) bool { return env["BUILDKITE"] == "true" },
+		fill: func(env map[string]string, e *Environment) {
+			e.Repo = RepoName(env["BUILDKITE_REPO"])
+			e.Branch = env["BUILDKITE_BRANCH"]
+			e.Sha = env["BUILDKITE_COMMIT"]
+			e.BuildID, _ = strconv.ParseInt(env["BUILDKITE_BUILD_NUMBER"], 10, 64)
+			e.Metadata[MetaRunner] = env["BUILDKITE_AGENT_NAME"]
+			e.Metadata[MetaBuildURL] = env["BUILDKITE_BUILD_URL"]
+
+			// agent tags are exported one variable each, such as BUILDKITE_AGENT_META_DATA_QUEUE=default
+			labels := make([]string, 0)
+			for key, value := range env {
+				if strings.HasPrefix(key, "BUILDKITE_AGENT_META_DATA_") {
+					labels = append(labels, strings.ToLower(strings.TrimPrefix(key, "BUILDKITE_AGENT_META_DATA_"))+"="+value)
+				}
+			}
+			sort.Strings(labels)
+			e.Metadata[MetaRunnerLabels] = strings.Join(labels, ",")
+		},
+	},
+	{
+		name:   "circleci",
+		detect: func(env map[string]string) bool { return env["CIRCLECI"] == "true" },
+		fill: func(env map[string]string, e *Environment) {
+			if env["CIRCLE_PROJECT_REPONAME"] != "" {
+				e.Repo = env["CIRCLE_PROJECT_USERNAME"] + "/" + env["CIRCLE_PROJECT_REPONAME"]
+			}
+			e.Branch = env["CIRCLE_BRANCH"]
+			e.Sha = env["CIRCLE_SHA1"]
+			e.BuildID, _ = strconv.ParseInt(env["CIRCLE_BUILD_NUM"], 10, 64)
+			e.Metadata[MetaBuildURL] = env["CIRCLE_BUILD_URL"]
+		},
+	},
+}
+
+// DetectEnvironment detects the repository, branch, sha and build of a run from CI environment variables given
+// as KEY=value, falling back to the git working tree at dir, along with metadata of the machine it runs on
+func DetectEnvironment(dir string, environ []string) Environment {
+	env := make(map[string]string)
+	for _, kv := range environ {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+
+	e := Environment{Metadata: make(map[string]string)}
+	for _, ci := range ciSystems {
+		if ci.detect(env) {
+			e.Metadata[MetaCI] = ci.name
+			ci.fill(env, &e)
+			break
+		}
+	}
+
+	if e.Sha == "" {
+		if out, err := command(dir, "git", "rev-parse", "HEAD"); err == nil {
+			e.Sha = strings.TrimSpace(out)
+		}
+	}
+	if e.Branch == "" {
+		// a detached HEAD, as checked out by most CI systems, has no branch
+		if out, err := command(dir, "git", "rev-parse", "--abbrev-ref", "HEAD"); err == nil && strings.TrimSpace(out) != "HEAD" {
+			e.Branch = strings.TrimSpace(out)
+		}
+	}
+	if e.Repo == "" {
+		if out, err := command(dir, "git", "config", "--get", "remote.origin.url"); err == nil {
+			e.Repo = RepoName(strings.TrimSpace(out))
+		}
+	}
+
+	e.Metadata[MetaGoVersion], e.Metadata[MetaGOOS], e.Metadata[MetaGOARCH] = goVersion(dir)
+	e.Metadata[MetaCPUs] = strconv.Itoa(runtime.NumCPU())
+	e.Metadata[MetaGOMAXPROCS] = first(env["GOMAXPROCS"], strconv.Itoa(runtime.NumCPU()))
+	e.Metadata[MetaHostname], _ = os.Hostname()
+
+	for key, value := range e.Metadata {
+		if value == "" {
+			delete(e.Metadata, key)
+		}
+	}
+	return e
+}
+
+// RepoName is the path of a repository in its remote URL, such as digitalocean/gocop for
+// git@github.com:digitalocean/gocop.git
+func RepoName(remote string) string {
+	if remote == "" {
+		return ""
+	}
+
+	path := remote
+	if u, err := url.Parse(remote); err == nil && u.Host != "" {
+		path = u.Path
+	} else if i := strings.Index(remote, ":"); i >= 0 {
+		// scp-like syntax of ssh remotes, user@host:path
+		path = remote[i+1:]
+	}
+	return strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+}
+
+// goVersion is the version, OS and architecture of the go command, which builds the tests, falling back to
+// those gocop was built with
+func goVersion(dir string) (string, string, string) {
+	out, err := command(dir, "go", "version")
+	fields := strings.Fields(out)
+	if err != nil || len(fields) < 4 {
+		return runtime.Version(), runtime.GOOS, runtime.GOARCH
+	}
+
+	platform := strings.SplitN(fields[3], "/", 2)
+	if len(platform) != 2 {
+		return fields[2], runtime.GOOS, runtime.GOARCH
+	}
+	return fields[2], platform[0], platform[1]
+}
+
+// gitlabTags joins the runner tags GitLab exports as a JSON array
+func gitlabTags(tags string) string {
+	var list []string
+	if json.Unmarshal([]byte(tags), &list) != nil {
+		return tags
+	}
+	return strings.Join(list, ",")
+}
+
+// first returns the first non-empty value
+func first(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,83 @@
+package gocop
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/poy/onpar"
+	"github.com/poy/onpar/expect"
+	"github.com/poy/onpar/matchers"
+)
+
+func TestDetectEnvironment(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	tests := []struct {
+		name    string
+		environ []string
+		want    Environment
+		labels  string
+	}{
+		{
+			name:    "github actions",
+			environ: []string{"GITHUB_ACTIONS=true", "GITHUB_REPOSITORY=digitalocean/gocop", "GITHUB_REF=refs/heads/main", "GITHUB_SHA=abc123", "GITHUB_RUN_ID=42", "RUNNER_NAME=runner-1"},
+			want:    Environment{Repo: "digitalocean/gocop", Branch: "main", Sha: "abc123", BuildID: 42},
+		},
+		{
+			name:    "gitlab",
+			environ: []string{"GITLAB_CI=true", "CI_PROJECT_PATH=group/gocop", "CI_COMMIT_REF_NAME=feature", "CI_COMMIT_SHA=abc123", "CI_PIPELINE_ID=7", `CI_RUNNER_TAGS=["docker", "linux"]`},
+			want:    Environment{Repo: "group/gocop", Branch: "feature", Sha: "abc123", BuildID: 7},
+			labels:  "docker,linux",
+		},
+		{
+			name:    "jenkins",
+			environ: []string{"JENKINS_URL=https://ci.example.com/", "GIT_URL=https://github.com/digitalocean/gocop.git", "GIT_BRANCH=origin/main", "GIT_COMMIT=abc123", "BUILD_NUMBER=9", "NODE_LABELS=linux  large"},
+			want:    Environment{Repo: "digitalocean/gocop", Branch: "main", Sha: "abc123", BuildID: 9},
+			labels:  "linux,large",
+		},
+		{
+			name:    "buildkite",
+			environ: []string{"BUILDKITE=true", "BUILDKITE_REPO=git@github.com:digitalocean/gocop.git", "BUILDKITE_BRANCH=main", "BUILDKITE_COMMIT=abc123", "BUILDKITE_BUILD_NUMBER=3", "BUILDKITE_AGENT_META_DATA_QUEUE=default", "BUILDKITE_AGENT_META_DATA_OS=linux"},
+			want:    Environment{Repo: "digitalocean/gocop", Branch: "main", Sha: "abc123", BuildID: 3},
+			labels:  "os=linux,queue=default",
+		},
+		{
+			name:    "circleci",
+			environ: []string{"CIRCLECI=true", "CIRCLE_PROJECT_USERNAME=digitalocean", "CIRCLE_PROJECT_REPONAME=gocop", "CIRCLE_BRANCH=main", "CIRCLE_SHA1=abc123", "CIRCLE_BUILD_NUM=5"},
+			want:    Environment{Repo: "digitalocean/gocop", Branch: "main", Sha: "abc123", BuildID: 5},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		o.Spec(tt.name, func(expect expect.Expectation) {
+			e := DetectEnvironment(".", tt.environ)
+			expect(e.Repo).To(matchers.Equal(tt.want.Repo))
+			expect(e.Branch).To(matchers.Equal(tt.want.Branch))
+			expect(e.Sha).To(matchers.Equal(tt.want.Sha))
+			expect(e.BuildID).To(matchers.Equal(tt.want.BuildID))
+			expect(e.Metadata[MetaRunnerLabels]).To(matchers.Equal(tt.labels))
+			expect(e.Metadata[MetaCI] != "").To(matchers.BeTrue())
+		})
+	}
+
+	o.Spec("falls back to the git working tree and go command", func(expect expect.Expectation) {
+		e := DetectEnvironment(".", []string{"GOMAXPROCS=3"})
+		expect(e.Sha).To(matchers.HaveLen(40))
+		expect(e.Metadata[MetaCI]).To(matchers.Equal(""))
+		expect(strings.HasPrefix(e.Metadata[MetaGoVersion], "go")).To(matchers.BeTrue())
+		expect(e.Metadata[MetaGOMAXPROCS]).To(matchers.Equal("3"))
+		expect(e.Metadata[MetaGOOS] != "").To(matchers.BeTrue())
+	})
+
+	o.Spec("names repositories by their remote path", func(expect expect.Expectation) {
+		expect(RepoName("https://github.com/digitalocean/gocop.git")).To(matchers.Equal("digitalocean/gocop"))
+		expect(RepoName("git@github.com:digitalocean/gocop.git")).To(matchers.Equal("digitalocean/gocop"))
+		expect(RepoName("ssh://git@gitlab.com/group/sub/gocop")).To(matchers.Equal("group/sub/gocop"))
+	})
+}
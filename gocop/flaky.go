@@ -1,47 +1,237 @@
 package gocop
 
 import (
-	"io/ioutil"
+	"bytes"
+	"io"
 	"log"
+	"sort"
+	"strconv"
+	"strings"
 )
 
+// FlakyPackage contains the outcomes of a package across multiple attempts
+type FlakyPackage struct {
+	Package  string          `json:"package"`
+	Attempts int             `json:"attempts"`
+	Failures int             `json:"failures"`
+	Runs     []PackageResult `json:"runs"`
+	Tests    []FlakyTest     `json:"tests,omitempty"`
+	Owners   []string        `json:"owners,omitempty"`
+}
+
+// FlakyTest contains the outcomes of a test across the attempts of its package
+type FlakyTest struct {
+	Name     string `json:"name"`
+	Attempts int    `json:"attempts"`
+	Failures int    `json:"failures"`
+}
+
+// FlakyPackages lists packages suspected of having flaky tests
+type FlakyPackages []FlakyPackage
+
+// Text lists package names one per line, grouped under their owners when known
+func (f FlakyPackages) Text() string {
+	names := make([]string, 0)
+	owners := make([][]string, 0)
+	for _, pkg := range f {
+		names = append(names, pkg.Package)
+		owners = append(owners, pkg.Owners)
+	}
+
+	if f.owned() {
+		return ownerText(GroupByOwner(names, owners))
+	}
+	return strings.Join(names, "\n")
+}
+
+// Records lists attempt counts and per-run outcomes with a header row, and owners when known
+func (f FlakyPackages) Records() [][]string {
+	owned := f.owned()
+	header := []string{"package", "attempts", "failures", "outcomes"}
+	if owned {
+		header = append(header, "owners")
+	}
+
+	records := [][]string{header}
+	for _, pkg := range f {
+		outcomes := make([]string, 0)
+		for _, run := range pkg.Runs {
+			outcomes = append(outcomes, run.Outcome)
+		}
+
+		record := []string{
+			pkg.Package,
+			strconv.Itoa(pkg.Attempts),
+			strconv.Itoa(pkg.Failures),
+			strings.Join(outcomes, " "),
+		}
+		if owned {
+			record = append(record, strings.Join(pkg.Owners, " "))
+		}
+		records = append(records, record)
+	}
+
+	return records
+}
+
+// owned reports whether owners were resolved for any package
+func (f FlakyPackages) owned() bool {
+	for _, pkg := range f {
+		if pkg.Owners != nil {
+			return true
+		}
+	}
+	return false
+}
+
 // Flaky reviews test output from multiple attempts and identifies potentially flaky packages
 func Flaky(runs ...[]byte) []string {
-	failCount := make(map[string]int)
-	runCount := len(runs)
-	flaky := make([]string, 0)
+	readers := make([]io.Reader, 0)
+	for _, run := range runs {
+		readers = append(readers, bytes.NewReader(run))
+	}
 
+	flaky, _ := FlakyReaders(readers...)
+	return flaky
+}
+
+// FlakyReaders reviews test output streamed from multiple attempts and identifies potentially flaky packages
+func FlakyReaders(runs ...io.Reader) ([]string, error) {
+	failCount := make(map[string]int)
 	for _, run := range runs {
-		pkgs := ParseFailed(run)
-		for _, pkg := range pkgs {
-			_, ok := failCount[pkg]
-			if !ok {
-				failCount[pkg] = 0
-			}
-			failCount[pkg] = failCount[pkg] + 1
+		err := countFailed(failCount, run)
+		if err != nil {
+			return nil, err
 		}
 	}
 
+	return flakyPackages(failCount, len(runs)), nil
+}
+
+// FlakyFile reviews test output from multiple files to identify flaky packages
+func FlakyFile(files ...string) []string {
+	failCount := make(map[string]int)
+	for _, file := range files {
+		f, err := Open(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = countFailed(failCount, f)
+		f.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	return flakyPackages(failCount, len(files))
+}
+
+// countFailed tallies the failed packages of a single run
+func countFailed(failCount map[string]int, run io.Reader) error {
+	pkgs, err := ParseReaderFailed(run)
+	for _, pkg := range pkgs {
+		failCount[pkg] = failCount[pkg] + 1
+	}
+
+	return err
+}
+
+// flakyPackages lists packages which failed in some, but not all, runs
+func flakyPackages(failCount map[string]int, runCount int) []string {
+	flaky := make([]string, 0)
 	for k, v := range failCount {
 		if v < runCount {
 			flaky = append(flaky, k)
 		}
 	}
+	sort.Strings(flaky)
 
 	return flaky
 }
 
-// FlakyFile reviews test output from multiple files to identify flaky packages
-func FlakyFile(files ...string) []string {
-	runs := make([][]byte, 0)
+// FlakyReport reviews test output streamed from multiple attempts and reports the outcomes of flaky packages
+func FlakyReport(runs ...io.Reader) (FlakyPackages, error) {
+	attempts := make(map[string][]PackageResult)
+	for i, run := range runs {
+		results, err := ReadResults(run)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, result := range results {
+			if _, ok := attempts[result.Package]; !ok {
+				attempts[result.Package] = make([]PackageResult, len(runs))
+			}
+			attempts[result.Package][i] = result
+		}
+	}
+
+	flaky := make(FlakyPackages, 0)
+	for pkg, results := range attempts {
+		failures := 0
+		for i := range results {
+			// a package missing from an attempt retains its name with an empty outcome
+			results[i].Package = pkg
+			if results[i].Outcome == ResultFail {
+				failures++
+			}
+		}
+
+		if failures > 0 && failures < len(runs) {
+			flaky = append(flaky, FlakyPackage{Package: pkg, Attempts: len(runs), Failures: failures, Runs: results, Tests: flakyTests(results)})
+		}
+	}
+	sort.Slice(flaky, func(i, j int) bool { return flaky[i].Package < flaky[j].Package })
+
+	return flaky, nil
+}
+
+// flakyTests lists tests which failed in some, but not all, attempts where their package ran
+func flakyTests(runs []PackageResult) []FlakyTest {
+	attempts := 0
+	failCount := make(map[string]int)
+	for _, run := range runs {
+		if run.Outcome == "" || run.Status == StatusBuildFailed || run.Status == StatusSetupFailed {
+			continue
+		}
+
+		attempts++
+		for _, test := range run.Tests {
+			if test.Outcome == ResultFail {
+				failCount[test.Name]++
+			}
+		}
+	}
+
+	tests := make([]FlakyTest, 0)
+	for name, failures := range failCount {
+		if failures < attempts {
+			tests = append(tests, FlakyTest{Name: name, Attempts: attempts, Failures: failures})
+		}
+	}
+	sort.Slice(tests, func(i, j int) bool { return tests[i].Name < tests[j].Name })
+
+	return tests
+}
+
+// FlakyFileReport reviews test output from multiple files and reports the outcomes of flaky packages
+func FlakyFileReport(files ...string) FlakyPackages {
+	readers := make([]io.Reader, 0)
 	for _, file := range files {
-		run, err := ioutil.ReadFile(file)
+		f, err := Open(file)
 		if err != nil {
 			log.Fatal(err)
 		}
+		defer f.Close()
 
-		runs = append(runs, run)
+		readers = append(readers, f)
 	}
 
-	return Flaky(runs...)
+	flaky, err := FlakyReport(readers...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return flaky
 }
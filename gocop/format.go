@@ -0,0 +1,158 @@
+package gocop
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// FormatText renders reports as plain text
+	FormatText = "text"
+	// FormatJSON renders reports as indented JSON
+	FormatJSON = "json"
+	// FormatYAML renders reports as YAML
+	FormatYAML = "yaml"
+	// FormatCSV renders reports as CSV with a header row
+	FormatCSV = "csv"
+	// FormatTemplate renders reports with a Go text/template
+	FormatTemplate = "template"
+	// FormatGitHub renders annotations as GitHub Actions workflow commands
+	FormatGitHub = "github"
+	// FormatGitLab renders annotations as a GitLab code quality report
+	FormatGitLab = "gitlab"
+)
+
+// Formats lists the supported output formats
+var Formats = []string{FormatText, FormatJSON, FormatYAML, FormatCSV, FormatTemplate, FormatGitHub, FormatGitLab}
+
+// Report is implemented by results which can be written in every output format
+type Report interface {
+	// Text renders the report as plain text without a trailing newline
+	Text() string
+	// Records renders the report as rows, the first being a header
+	Records() [][]string
+}
+
+// Write renders a report to w in the given format, executing tmpl for FormatTemplate
+func Write(w io.Writer, format, tmpl string, report Report) error {
+	switch format {
+	case FormatText:
+		text := report.Text()
+		if text == "" {
+			return nil
+		}
+		_, err := fmt.Fprintf(w, "%s\n", text)
+		return err
+	case FormatJSON:
+		content, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", content)
+		return err
+	case FormatYAML:
+		content, err := MarshalYAML(report)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, content)
+		return err
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		err := cw.WriteAll(report.Records())
+		if err != nil {
+			return err
+		}
+		return cw.Error()
+	case FormatTemplate:
+		t, err := template.New(format).Parse(tmpl)
+		if err != nil {
+			return err
+		}
+		return t.Execute(w, report)
+	case FormatGitHub, FormatGitLab:
+		annotator, ok := report.(Annotator)
+		if !ok {
+			return fmt.Errorf("output format %q is not supported for this report", format)
+		}
+
+		// source paths are resolved against the module in the working directory when there is one
+		module, _ := ModulePath(".")
+		if format == FormatGitHub {
+			return writeGitHub(w, module, annotator.Annotations())
+		}
+		return writeGitLab(w, module, annotator.Annotations())
+	}
+
+	return fmt.Errorf("unknown output format %q, expected one of %s", format, strings.Join(Formats, ", "))
+}
+
+// MarshalYAML renders a value as YAML with the same field names and order as encoding/json
+func MarshalYAML(v interface{}) (string, error) {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+	value, err := yamlValue(dec)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := yaml.Marshal(value)
+	return string(out), err
+}
+
+// yamlValue decodes the next JSON value, keeping the order of object keys in a yaml.MapSlice
+func yamlValue(dec *json.Decoder) (interface{}, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := token.(type) {
+	case json.Delim:
+		if t == '[' {
+			list := make([]interface{}, 0)
+			for dec.More() {
+				item, err := yamlValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, item)
+			}
+			_, err = dec.Token()
+			return list, err
+		}
+
+		object := yaml.MapSlice{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := yamlValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			object = append(object, yaml.MapItem{Key: key, Value: value})
+		}
+		_, err = dec.Token()
+		return object, err
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		return t.Float64()
+	}
+	return token, nil
+}
@@ -0,0 +1,96 @@
+package gocop
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/poy/onpar"
+	"github.com/poy/onpar/expect"
+	"github.com/poy/onpar/matchers"
+	"gopkg.in/yaml.v2"
+)
+
+func TestWrite(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	report := FlakyPackages{{
+		Package:  "github.com/digitalocean/gocop/sample/flaky",
+		Attempts: 2,
+		Failures: 1,
+		Runs: []PackageResult{
+			{Package: "github.com/digitalocean/gocop/sample/flaky", Outcome: ResultFail, Duration: 0.488},
+			{Package: "github.com/digitalocean/gocop/sample/flaky", Outcome: ResultPass, Duration: 0.5, Coverage: 50},
+		},
+	}}
+
+	tests := []struct {
+		name   string
+		format string
+		tmpl   string
+		want   string
+	}{
+		{
+			name:   "writes text",
+			format: FormatText,
+			want:   "github.com/digitalocean/gocop/sample/flaky\n",
+		},
+		{
+			name:   "writes yaml",
+			format: FormatYAML,
+			want: `- package: github.com/digitalocean/gocop/sample/flaky
+  attempts: 2
+  failures: 1
+  runs:
+  - package: github.com/digitalocean/gocop/sample/flaky
+    outcome: fail
+    duration: 0.488
+    coverage: 0
+  - package: github.com/digitalocean/gocop/sample/flaky
+    outcome: pass
+    duration: 0.5
+    coverage: 50
+`,
+		},
+		{
+			name:   "writes csv",
+			format: FormatCSV,
+			want:   "package,attempts,failures,outcomes\ngithub.com/digitalocean/gocop/sample/flaky,2,1,fail pass\n",
+		},
+		{
+			name:   "writes template",
+			format: FormatTemplate,
+			tmpl:   `{{range .}}{{.Package}} {{.Failures}}/{{.Attempts}}{{end}}`,
+			want:   "github.com/digitalocean/gocop/sample/flaky 1/2",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		o.Spec(tt.name, func(expect expect.Expectation) {
+			var buf bytes.Buffer
+			err := Write(&buf, tt.format, tt.tmpl, report)
+			expect(err).To(matchers.BeNil())
+			expect(buf.String()).To(matchers.Equal(tt.want))
+		})
+	}
+
+	o.Spec("writes yaml which reads back the same values", func(expect expect.Expectation) {
+		test := TestCase{
+			Name:    "TestA: #1",
+			Outcome: "true",
+			Output:  []string{"  indented: value # not a comment", "panic: boom\n\tmain.go:3", "null", "- item", "0.5"},
+		}
+		content, err := MarshalYAML(test)
+		expect(err).To(matchers.BeNil())
+
+		var got TestCase
+		err = yaml.Unmarshal([]byte(content), &got)
+		expect(err).To(matchers.BeNil())
+		expect(got).To(matchers.Equal(test))
+	})
+}
@@ -75,3 +75,47 @@ func TestFlakyFile(t *testing.T) {
 		})
 	}
 }
+
+func TestParseFileResults(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "reads go test output",
+			input: "testdata/run0.txt",
+			want:  "github.com/digitalocean/gocop/sample/fail\ngithub.com/digitalocean/gocop/sample/failbuild\ngithub.com/digitalocean/gocop/sample/flaky",
+		},
+		{
+			name:  "reads go test -json output",
+			input: "testdata/run0.json",
+			want:  "github.com/digitalocean/gocop/sample/fail\ngithub.com/digitalocean/gocop/sample/failbuild\ngithub.com/digitalocean/gocop/sample/flaky",
+		},
+		{
+			name:  "reads JUnit XML",
+			input: "testdata/run0.xml",
+			want:  "github.com/digitalocean/gocop/sample/fail\ngithub.com/digitalocean/gocop/sample/failbuild\ngithub.com/digitalocean/gocop/sample/flaky",
+		},
+		{
+			name:  "reads gotestsum output",
+			input: "testdata/run0.gotestsum.txt",
+			want:  "sample/fail\nsample/failbuild\nsample/flaky",
+		},
+	}
+
+	for _, tt := range tests {
+		o.Spec(tt.name, func(expect expect.Expectation) {
+			got := gocop.ParseFileResults(tt.input)
+			expect(got.Outcome(gocop.ResultFail).Text()).To(Equal(tt.want))
+			expect(len(got)).To(Equal(5))
+		})
+	}
+}
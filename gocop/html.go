@@ -0,0 +1,144 @@
+package gocop
+
+import (
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HTMLReport contains the results of a run and its retries for rendering as a static page
+type HTMLReport struct {
+	Summary  Summary
+	Attempts int
+	Flaky    int
+	Packages []HTMLPackage
+}
+
+// HTMLPackage contains the outcome of a package in each attempt, empty when it was not run
+type HTMLPackage struct {
+	Package string
+	Flaky   bool
+	Runs    []PackageResult
+}
+
+// NewHTMLReport collects the results of a run followed by its retries
+func NewHTMLReport(attempts ...PackageResults) HTMLReport {
+	report := HTMLReport{Attempts: len(attempts)}
+	if len(attempts) > 0 {
+		report.Summary = Summarize(attempts[0])
+	}
+
+	index := make(map[string]int)
+	for i, results := range attempts {
+		for _, result := range results {
+			j, ok := index[result.Package]
+			if !ok {
+				j = len(report.Packages)
+				index[result.Package] = j
+				report.Packages = append(report.Packages, HTMLPackage{Package: result.Package, Runs: make([]PackageResult, len(attempts))})
+			}
+			report.Packages[j].Runs[i] = result
+		}
+	}
+
+	// retries usually only rerun failures, so only attempts which ran are compared
+	for i := range report.Packages {
+		pkg := &report.Packages[i]
+		passed, failed := false, false
+		for _, run := range pkg.Runs {
+			passed = passed || run.Outcome == ResultPass
+			failed = failed || run.Outcome == ResultFail
+		}
+		pkg.Flaky = passed && failed
+		if pkg.Flaky {
+			report.Flaky++
+		}
+	}
+	sort.SliceStable(report.Packages, func(i, j int) bool { return report.Packages[i].Package < report.Packages[j].Package })
+
+	return report
+}
+
+// WriteHTML renders a self-contained HTML page for a report
+func WriteHTML(w io.Writer, report HTMLReport) error {
+	return htmlTemplate.Execute(w, report)
+}
+
+var htmlTemplate = template.Must(template.New("html").Funcs(template.FuncMap{
+	"seconds": func(f float64) string { return strconv.FormatFloat(f, 'f', 3, 64) + "s" },
+	"percent": func(f float64) string { return strconv.FormatFloat(f, 'f', 1, 64) },
+	"join":    strings.Join,
+	"attempt": func(i int) int { return i + 1 },
+	"attempts": func(n int) []int {
+		attempts := make([]int, n)
+		for i := range attempts {
+			attempts[i] = i + 1
+		}
+		return attempts
+	},
+	"last": func(runs []PackageResult) PackageResult {
+		for i := len(runs) - 1; i >= 0; i-- {
+			if runs[i].Outcome != "" {
+				return runs[i]
+			}
+		}
+		return PackageResult{}
+	},
+	"hasOutput": func(runs []PackageResult) bool {
+		for _, run := range runs {
+			if run.Outcome == ResultFail && len(run.Output) > 0 {
+				return true
+			}
+		}
+		return false
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Test report</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #24292e; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 0.4em 0.6em; border-bottom: 1px solid #e1e4e8; vertical-align: top; }
+th { background: #f6f8fa; }
+tr.flaky { background: #fff8e1; }
+.pass { color: #22863a; font-weight: bold; }
+.fail { color: #cb2431; font-weight: bold; }
+.skip, .none { color: #6a737d; }
+.badge { display: inline-block; padding: 0 0.5em; border-radius: 1em; background: #f9c513; font-size: 0.8em; }
+.bar { width: 8em; height: 0.8em; background: #e1e4e8; display: inline-block; vertical-align: middle; }
+.bar div { height: 100%; background: #2188ff; }
+pre { background: #f6f8fa; padding: 0.8em; overflow-x: auto; font-size: 0.85em; }
+summary { cursor: pointer; }
+</style>
+</head>
+<body>
+<h1>Test report</h1>
+<p>
+{{.Summary.Packages}} packages: {{.Summary.Passed}} passed, {{.Summary.Failed}} failed, {{.Summary.Skipped}} skipped in {{seconds .Summary.Duration}}.
+{{.Attempts}} attempts, {{.Flaky}} flaky packages.
+</p>
+<table>
+<thead>
+<tr><th>Package</th>{{range attempts .Attempts}}<th>Attempt {{.}}</th>{{end}}<th>Coverage</th></tr>
+</thead>
+<tbody>
+{{range .Packages}}
+<tr{{if .Flaky}} class="flaky"{{end}}>
+<td><code>{{.Package}}</code>{{if .Flaky}} <span class="badge">flaky</span>{{end}}
+{{if hasOutput .Runs}}{{range $i, $run := .Runs}}{{if and (eq $run.Outcome "fail") $run.Output}}
+<details><summary>Attempt {{attempt $i}} output</summary><pre>{{join $run.Output "\n"}}</pre></details>
+{{end}}{{end}}{{end}}
+</td>
+{{range .Runs}}<td>{{if .Outcome}}<span class="{{.Outcome}}">{{.Outcome}}</span><br>{{if .Status}}{{.Status}}{{else}}{{seconds .Duration}}{{end}}{{else}}<span class="none">&ndash;</span>{{end}}</td>{{end}}
+{{$last := last .Runs}}<td>{{if $last.Coverage}}<span class="bar"><div style="width: {{percent $last.Coverage}}%"></div></span> {{percent $last.Coverage}}%{{end}}</td>
+</tr>
+{{end}}
+</tbody>
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,48 @@
+package gocop
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/poy/onpar"
+	"github.com/poy/onpar/expect"
+	"github.com/poy/onpar/matchers"
+)
+
+func TestHTMLReport(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	run := PackageResults{
+		{Package: "example.com/pass", Outcome: ResultPass, Duration: 1, Coverage: 75},
+		{Package: "example.com/flaky", Outcome: ResultFail, Duration: 2, Output: []string{"flaky_test.go:10: <unexpected>"}},
+		{Package: "example.com/fail", Outcome: ResultFail, Duration: 3},
+	}
+	retry := PackageResults{
+		{Package: "example.com/flaky", Outcome: ResultPass, Duration: 2},
+		{Package: "example.com/fail", Outcome: ResultFail, Duration: 3},
+	}
+
+	o.Spec("marks packages which passed and failed as flaky", func(expect expect.Expectation) {
+		report := NewHTMLReport(run, retry)
+		expect(report.Attempts).To(matchers.Equal(2))
+		expect(report.Flaky).To(matchers.Equal(1))
+		expect(report.Packages[0].Package).To(matchers.Equal("example.com/fail"))
+		expect(report.Packages[0].Flaky).To(matchers.BeFalse())
+		expect(report.Packages[1].Flaky).To(matchers.BeTrue())
+		expect(report.Packages[2].Runs[1].Outcome).To(matchers.Equal(""))
+	})
+
+	o.Spec("renders escaped failure logs and coverage", func(expect expect.Expectation) {
+		var buf bytes.Buffer
+		err := WriteHTML(&buf, NewHTMLReport(run, retry))
+		expect(err).To(matchers.BeNil())
+		expect(buf.String()).To(matchers.ContainSubstring("flaky_test.go:10: &lt;unexpected&gt;"))
+		expect(buf.String()).To(matchers.ContainSubstring(`width: 75.0%`))
+		expect(buf.String()).To(matchers.ContainSubstring("<th>Attempt 2</th>"))
+	})
+}
@@ -0,0 +1,297 @@
+package gocop
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// InputText identifies plain go test output, including gotestsum's pkgname formats
+	InputText = "text"
+	// InputJSON identifies go test -json output, as also written by gotestsum --jsonfile
+	InputJSON = "json"
+	// InputJUnit identifies JUnit XML reports
+	InputJUnit = "junit"
+)
+
+// TestEvent is a single event emitted by go test -json
+type TestEvent struct {
+	Time       time.Time
+	Action     string
+	Package    string
+	ImportPath string
+	Test       string
+	Elapsed    float64
+	Output     string
+}
+
+// DetectInput peeks at the start of test output to identify its format
+func DetectInput(r *bufio.Reader) string {
+	head, _ := r.Peek(512)
+	head = bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")), " \t\r\n")
+
+	switch {
+	case bytes.HasPrefix(head, []byte("<")):
+		return InputJUnit
+	case bytes.HasPrefix(head, []byte(`{"`)) && bytes.Contains(head, []byte(`"Action"`)):
+		return InputJSON
+	}
+	return InputText
+}
+
+// EachResult streams package results from test output in any supported input format to fn
+func EachResult(r io.Reader, fn func(PackageResult) error) error {
+	br := bufio.NewReaderSize(r, MaxLineLength)
+	switch DetectInput(br) {
+	case InputJSON:
+		return eachEventResult(br, nil, fn)
+	case InputJUnit:
+		return eachJUnitResult(br, fn)
+	}
+
+	scanner := NewScanner(br)
+	for scanner.Scan() {
+		result, err := NewPackageResult(scanner.Result())
+		if err != nil {
+			return err
+		}
+		result.Tests = scanner.Tests()
+		result.Output = scanner.Output()
+		result.Shuffle = scanner.Shuffle()
+
+		err = fn(result)
+		if err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// eachEventResult replays the output of each package from go test -json through a Scanner, passing each
+// event to onEvent first when set
+func eachEventResult(r io.Reader, onEvent func(TestEvent), fn func(PackageResult) error) error {
+	scanners := make(map[string]*Scanner)
+	builds := newLineScanner()
+	scanner := func(pkg string) *Scanner {
+		s, ok := scanners[pkg]
+		if !ok {
+			s = newLineScanner()
+			scanners[pkg] = s
+		}
+		return s
+	}
+
+	dec := json.NewDecoder(r)
+	for {
+		var event TestEvent
+		err := dec.Decode(&event)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if onEvent != nil {
+			onEvent(event)
+		}
+
+		if event.Package == "" {
+			// build output is reported against the import path of the test binary
+			if event.ImportPath != "" {
+				scanLines(builds, event.Output)
+			}
+			continue
+		}
+
+		s := scanner(event.Package)
+		if event.Action == "output" {
+			scanLines(s, event.Output)
+			continue
+		}
+		if event.Test != "" || (event.Action != "pass" && event.Action != "fail" && event.Action != "skip") {
+			continue
+		}
+
+		result := PackageResult{Package: event.Package, Duration: event.Elapsed}
+		if s.result != nil && s.result[1] == event.Package {
+			result, err = NewPackageResult(s.result)
+			if err != nil {
+				return err
+			}
+			result.Tests = s.resultTests
+			result.Output = s.resultOutput
+			result.Shuffle = s.resultShuffle
+		} else {
+			result.Outcome = map[string]string{"pass": ResultPass, "fail": ResultFail, "skip": ResultSkip}[event.Action]
+			result.Tests = s.tests
+			result.Output = s.output
+			result.Shuffle = s.shuffle
+		}
+		if build, ok := builds.build[event.Package]; ok && len(result.Output) == 0 {
+			result.Output = build
+		}
+		delete(builds.build, event.Package)
+		delete(scanners, event.Package)
+
+		err = fn(result)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// newLineScanner returns a Scanner which is fed lines directly rather than from a reader
+func newLineScanner() *Scanner {
+	return &Scanner{current: -1, build: make(map[string][]string)}
+}
+
+func scanLines(s *Scanner, output string) {
+	if output == "" {
+		return
+	}
+	output = strings.TrimSuffix(string(Normalize([]byte(output))), "\n")
+	for _, line := range strings.Split(output, "\n") {
+		s.scanLine([]byte(line))
+	}
+}
+
+// eachJUnitResult converts each test suite of a JUnit XML report into package results by classname
+func eachJUnitResult(r io.Reader, fn func(PackageResult) error) error {
+	dec := xml.NewDecoder(r)
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "testsuite" {
+			continue
+		}
+
+		var suite JUnitTestSuite
+		err = dec.DecodeElement(&suite, &start)
+		if err != nil {
+			return err
+		}
+
+		for _, result := range junitResults(suite) {
+			err = fn(result)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// junitResults groups the test cases of a suite into packages, as suites need not map to a single package
+func junitResults(suite JUnitTestSuite) PackageResults {
+	results := make(PackageResults, 0)
+	index := make(map[string]int)
+	for _, tc := range suite.TestCases {
+		pkg := tc.Classname
+		if pkg == "" {
+			pkg = suite.Name
+		}
+		i, ok := index[pkg]
+		if !ok {
+			i = len(results)
+			index[pkg] = i
+			results = append(results, PackageResult{Package: pkg, Outcome: ResultPass})
+		}
+		result := &results[i]
+
+		elapsed, _ := strconv.ParseFloat(tc.Time, 64)
+		result.Duration += elapsed
+
+		switch {
+		case tc.Error != nil && strings.HasPrefix(tc.Name, "["):
+			// package level errors, such as build failures, are not tests
+			result.Outcome = ResultFail
+			result.Status = strings.Trim(tc.Name, "[]")
+			result.Output = splitOutput(tc.Error.Body)
+			continue
+		case tc.Failure != nil && tc.Name == "[package]":
+			result.Outcome = ResultFail
+			result.Output = splitOutput(tc.Failure.Body)
+			continue
+		}
+
+		test := TestCase{Name: tc.Name, Outcome: ResultPass, Duration: elapsed}
+		var msg *JUnitMessage
+		switch {
+		case tc.Failure != nil:
+			test.Outcome, msg = ResultFail, tc.Failure
+		case tc.Error != nil:
+			test.Outcome, msg = ResultFail, tc.Error
+		case tc.Skipped != nil:
+			test.Outcome, msg = ResultSkip, tc.Skipped
+		}
+		if msg != nil {
+			test.Output = junitOutput(msg)
+		}
+		if test.Outcome == ResultFail {
+			result.Outcome = ResultFail
+		}
+		result.Tests = append(result.Tests, test)
+	}
+
+	// a suite without any test cases is a package without tests
+	if len(results) == 0 {
+		results = append(results, PackageResult{Package: suite.Name, Outcome: ResultSkip, Status: StatusNoTestFiles})
+	}
+
+	for i := range results {
+		if results[i].Package == suite.Name {
+			results[i].Duration, _ = strconv.ParseFloat(suite.Time, 64)
+			if suite.SystemOut != nil && len(results[i].Output) == 0 {
+				results[i].Output = splitOutput(suite.SystemOut.Data)
+			}
+		}
+		if suite.Properties != nil {
+			for _, property := range suite.Properties.Property {
+				if property.Name == "coverage" {
+					results[i].Coverage, _ = strconv.ParseFloat(property.Value, 64)
+				}
+			}
+		}
+		if allSkipped(results[i].Tests) && results[i].Outcome == ResultPass {
+			results[i].Outcome = ResultSkip
+		}
+	}
+
+	return results
+}
+
+func junitOutput(msg *JUnitMessage) []string {
+	if strings.TrimSpace(msg.Body) != "" {
+		return splitOutput(msg.Body)
+	}
+	if msg.Message != "" {
+		return []string{msg.Message}
+	}
+	return nil
+}
+
+func splitOutput(output string) []string {
+	return strings.Split(strings.TrimRight(output, "\r\n"), "\n")
+}
+
+func allSkipped(tests []TestCase) bool {
+	for _, test := range tests {
+		if test.Outcome != ResultSkip {
+			return false
+		}
+	}
+	return len(tests) > 0
+}
@@ -0,0 +1,369 @@
+package gocop
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// TrackerGitHub files issues in GitHub Issues
+	TrackerGitHub = "github"
+	// TrackerJira files issues in Jira
+	TrackerJira = "jira"
+)
+
+const (
+	// IssueOpen marks a tracked issue which is still open
+	IssueOpen = "open"
+	// IssueClosed marks a tracked issue closed once its test was stable
+	IssueClosed = "closed"
+)
+
+// IssueTracker files, updates and closes issues for flaky tests
+type IssueTracker interface {
+	Name() string
+	Open(repo string, o FlakyOccurrence) (key, url string, err error)
+	Comment(key, body string) error
+	Close(key, comment string) error
+}
+
+// IssueSync decides which issues to open, update and close for flaky tests
+type IssueSync struct {
+	Tracker IssueTracker
+	Repo    string
+	// StableRuns is how many passing runs after its last flaky run close the issue of a test
+	StableRuns int
+	// Passes counts the passing runs of a package after a time
+	Passes func(pkg string, since time.Time) (int, error)
+	Now    time.Time
+}
+
+// Sync files issues for new flaky tests, updates issues with new occurrences and closes issues of stable tests,
+// returning the issues which changed
+//
+// The issues changed before an error are returned with it, so they can still be recorded.
+func (s IssueSync) Sync(occurrences []FlakyOccurrence, tracked []TrackedIssue) ([]TrackedIssue, error) {
+	issues := make(map[string]TrackedIssue)
+	keys := make([]string, 0)
+	for _, issue := range tracked {
+		key := issue.Package + "\x00" + issue.Test
+		issues[key] = issue
+		keys = append(keys, key)
+	}
+
+	opened := make(map[string]bool)
+	updated := make(map[string]bool)
+	changed := func() []TrackedIssue {
+		result := make([]TrackedIssue, 0)
+		for _, key := range keys {
+			if opened[key] || updated[key] {
+				result = append(result, issues[key])
+			}
+		}
+		return result
+	}
+	for _, o := range occurrences {
+		key := o.Package + "\x00" + o.Test
+		issue, ok := issues[key]
+
+		switch {
+		case !ok || (issue.State == IssueClosed && o.LastSeen.After(issue.Updated)):
+			// a test flaky again after its issue was closed gets a new issue
+			k, url, err := s.Tracker.Open(s.Repo, o)
+			if err != nil {
+				return changed(), err
+			}
+			if !ok {
+				keys = append(keys, key)
+			}
+			issues[key] = TrackedIssue{
+				Repo:        s.Repo,
+				Package:     o.Package,
+				Test:        o.Test,
+				Tracker:     s.Tracker.Name(),
+				Key:         k,
+				URL:         url,
+				State:       IssueOpen,
+				Occurrences: o.Runs,
+				LastSeen:    o.LastSeen,
+				Created:     s.Now,
+				Updated:     s.Now,
+			}
+			opened[key] = true
+
+		case issue.State == IssueOpen && o.LastSeen.After(issue.LastSeen):
+			err := s.Tracker.Comment(issue.Key, occurrenceComment(o, s.Tracker.Name()))
+			if err != nil {
+				return changed(), err
+			}
+			issue.Occurrences = o.Runs
+			issue.LastSeen = o.LastSeen
+			issue.Updated = s.Now
+			issues[key] = issue
+			updated[key] = true
+		}
+	}
+
+	for _, key := range keys {
+		issue := issues[key]
+		if issue.State == IssueOpen && !opened[key] {
+			passes, err := s.Passes(issue.Package, issue.LastSeen)
+			if err != nil {
+				return changed(), err
+			}
+			if passes >= s.StableRuns {
+				err = s.Tracker.Close(issue.Key, fmt.Sprintf("Stable for %d runs since it was last flaky on %s, closing.",
+					passes, issue.LastSeen.UTC().Format(time.RFC3339)))
+				if err != nil {
+					return changed(), err
+				}
+				issue.State = IssueClosed
+				issue.Updated = s.Now
+				issues[key] = issue
+				updated[key] = true
+			}
+		}
+	}
+
+	return changed(), nil
+}
+
+// issueTitle names the flaky test or package of an issue
+func issueTitle(o FlakyOccurrence) string {
+	if o.Test != "" {
+		return fmt.Sprintf("Flaky test %s in %s", o.Test, o.Package)
+	}
+	return "Flaky package " + o.Package
+}
+
+// issueBody describes a flaky test, with output in a code block of the tracker's markup
+func issueBody(repo string, o FlakyOccurrence, tracker string) string {
+	subject := inlineCode(o.Package, tracker)
+	if o.Test != "" {
+		subject = inlineCode(o.Test, tracker) + " in " + subject
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s was flaky in %d runs of %s between %s and %s.\n\n", subject, o.Runs, repo,
+		o.FirstSeen.UTC().Format(time.RFC3339), o.LastSeen.UTC().Format(time.RFC3339))
+	if o.FirstSha != "" {
+		fmt.Fprintf(&b, "First seen at %s.\n\n", o.FirstSha)
+	}
+	if len(o.Output) > 0 {
+		b.WriteString("Latest failure output:\n\n")
+		b.WriteString(codeBlock(o.Output, tracker))
+		b.WriteString("\n")
+	}
+	b.WriteString("Filed by gocop, which updates this issue on new occurrences and closes it once the test is stable.")
+
+	return b.String()
+}
+
+// occurrenceComment reports new flaky runs on an open issue
+func occurrenceComment(o FlakyOccurrence, tracker string) string {
+	comment := fmt.Sprintf("Flaky again on %s, now in %d runs since %s.", o.LastSeen.UTC().Format(time.RFC3339),
+		o.Runs, o.FirstSeen.UTC().Format(time.RFC3339))
+	if o.LastSha != "" {
+		comment += " Latest at " + o.LastSha + "."
+	}
+	if len(o.Output) > 0 {
+		comment += "\n\n" + codeBlock(o.Output, tracker)
+	}
+	return comment
+}
+
+func inlineCode(s, tracker string) string {
+	if tracker == TrackerJira {
+		return "{{" + s + "}}"
+	}
+	return "`" + s + "`"
+}
+
+func codeBlock(lines []string, tracker string) string {
+	if tracker == TrackerJira {
+		return "{noformat}\n" + strings.Join(lines, "\n") + "\n{noformat}\n"
+	}
+	return "```\n" + strings.Replace(strings.Join(lines, "\n"), "```", "` ` `", -1) + "\n```\n"
+}
+
+// GitHubTracker files issues in a GitHub repository
+type GitHubTracker struct {
+	// URL is the base URL of the API, https://api.github.com unless using GitHub Enterprise
+	URL string
+	// Repo is the repository issues are filed in, as owner/name
+	Repo   string
+	Token  string
+	Labels []string
+}
+
+// Name identifies the tracker
+func (g GitHubTracker) Name() string {
+	return TrackerGitHub
+}
+
+// Open files an issue for a flaky test
+func (g GitHubTracker) Open(repo string, o FlakyOccurrence) (string, string, error) {
+	var issue struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+	}
+	labels := g.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+
+	err := g.do(http.MethodPost, "/issues", map[string]interface{}{
+		"title":  issueTitle(o),
+		"body":   issueBody(repo, o, TrackerGitHub),
+		"labels": labels,
+	}, &issue)
+	return fmt.Sprint(issue.Number), issue.HTMLURL, err
+}
+
+// Comment adds a comment to an issue
+func (g GitHubTracker) Comment(key, body string) error {
+	return g.do(http.MethodPost, "/issues/"+key+"/comments", map[string]string{"body": body}, nil)
+}
+
+// Close comments on and closes an issue
+func (g GitHubTracker) Close(key, comment string) error {
+	err := g.Comment(key, comment)
+	if err != nil {
+		return err
+	}
+	return g.do(http.MethodPatch, "/issues/"+key, map[string]string{"state": "closed"}, nil)
+}
+
+func (g GitHubTracker) do(method, path string, body, result interface{}) error {
+	url := strings.TrimSuffix(g.URL, "/") + "/repos/" + g.Repo + path
+	return doJSON(method, url, body, result, func(req *http.Request) {
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("Authorization", "Bearer "+g.Token)
+	})
+}
+
+// JiraTracker files issues in a Jira project
+type JiraTracker struct {
+	URL     string
+	Project string
+	// IssueType is the type of filed issues, such as Bug
+	IssueType string
+	// User authenticates with Token as an API token, otherwise Token is sent as a bearer token
+	User  string
+	Token string
+	// CloseTransition is the name of the workflow transition closing an issue, such as Done
+	CloseTransition string
+	Labels          []string
+}
+
+// Name identifies the tracker
+func (j JiraTracker) Name() string {
+	return TrackerJira
+}
+
+// Open files an issue for a flaky test
+func (j JiraTracker) Open(repo string, o FlakyOccurrence) (string, string, error) {
+	var issue struct {
+		Key string `json:"key"`
+	}
+	labels := j.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+
+	err := j.do(http.MethodPost, "/issue", map[string]interface{}{
+		"fields": map[string]interface{}{
+			"project":     map[string]string{"key": j.Project},
+			"summary":     issueTitle(o),
+			"description": issueBody(repo, o, TrackerJira),
+			"issuetype":   map[string]string{"name": j.IssueType},
+			"labels":      labels,
+		},
+	}, &issue)
+	return issue.Key, strings.TrimSuffix(j.URL, "/") + "/browse/" + issue.Key, err
+}
+
+// Comment adds a comment to an issue
+func (j JiraTracker) Comment(key, body string) error {
+	return j.do(http.MethodPost, "/issue/"+key+"/comment", map[string]string{"body": body}, nil)
+}
+
+// Close comments on an issue and moves it through the close transition
+func (j JiraTracker) Close(key, comment string) error {
+	err := j.Comment(key, comment)
+	if err != nil {
+		return err
+	}
+
+	var transitions struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"transitions"`
+	}
+	err = j.do(http.MethodGet, "/issue/"+key+"/transitions", nil, &transitions)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range transitions.Transitions {
+		if strings.EqualFold(t.Name, j.CloseTransition) {
+			return j.do(http.MethodPost, "/issue/"+key+"/transitions", map[string]interface{}{
+				"transition": map[string]string{"id": t.ID},
+			}, nil)
+		}
+	}
+	return fmt.Errorf("issue %s has no %q transition", key, j.CloseTransition)
+}
+
+func (j JiraTracker) do(method, path string, body, result interface{}) error {
+	url := strings.TrimSuffix(j.URL, "/") + "/rest/api/2" + path
+	return doJSON(method, url, body, result, func(req *http.Request) {
+		if j.User != "" {
+			req.SetBasicAuth(j.User, j.Token)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+j.Token)
+		}
+	})
+}
+
+// doJSON sends a JSON request to a REST API, decoding the response into result when set
+func doJSON(method, url string, body, result interface{}, auth func(*http.Request)) error {
+	var r io.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(content)
+	}
+
+	req, err := http.NewRequest(method, url, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	auth(req)
+
+	client := http.Client{Timeout: time.Minute}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		content, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("%s %s failed: %s: %s", method, url, res.Status, bytes.TrimSpace(content))
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(result)
+}
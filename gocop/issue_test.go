@@ -0,0 +1,150 @@
+package gocop
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/poy/onpar"
+	"github.com/poy/onpar/expect"
+	"github.com/poy/onpar/matchers"
+)
+
+type fakeTracker struct {
+	opened   []FlakyOccurrence
+	comments []string
+	closed   []string
+}
+
+func (f *fakeTracker) Name() string {
+	return "fake"
+}
+
+func (f *fakeTracker) Open(repo string, o FlakyOccurrence) (string, string, error) {
+	f.opened = append(f.opened, o)
+	key := fmt.Sprint(len(f.opened))
+	return key, "https://issues.example.com/" + key, nil
+}
+
+func (f *fakeTracker) Comment(key, body string) error {
+	f.comments = append(f.comments, key)
+	return nil
+}
+
+func (f *fakeTracker) Close(key, comment string) error {
+	f.closed = append(f.closed, key)
+	return nil
+}
+
+func TestIssueSync(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	now := time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	passes := map[string]int{"example.com/stable": 25, "example.com/again": 25}
+	sync := func(tracker IssueTracker) IssueSync {
+		return IssueSync{
+			Tracker:    tracker,
+			Repo:       "gocop",
+			StableRuns: 20,
+			Passes: func(pkg string, since time.Time) (int, error) {
+				return passes[pkg], nil
+			},
+			Now: now,
+		}
+	}
+
+	o.Spec("opens, updates and closes issues", func(expect expect.Expectation) {
+		occurrences := []FlakyOccurrence{
+			{Package: "example.com/new", Test: "TestNew", Runs: 1, LastSeen: now.Add(-day)},
+			{Package: "example.com/seen", Test: "TestSeen", Runs: 3, LastSeen: now.Add(-day)},
+			{Package: "example.com/same", Test: "TestSame", Runs: 2, LastSeen: now.Add(-2 * day)},
+			{Package: "example.com/again", Test: "TestAgain", Runs: 1, LastSeen: now.Add(-day)},
+		}
+		tracked := []TrackedIssue{
+			{Package: "example.com/seen", Test: "TestSeen", Key: "10", State: IssueOpen, Occurrences: 2, LastSeen: now.Add(-3 * day)},
+			{Package: "example.com/same", Test: "TestSame", Key: "11", State: IssueOpen, Occurrences: 2, LastSeen: now.Add(-2 * day)},
+			{Package: "example.com/stable", Test: "TestStable", Key: "12", State: IssueOpen, LastSeen: now.Add(-9 * day)},
+			{Package: "example.com/again", Test: "TestAgain", Key: "13", State: IssueClosed, Updated: now.Add(-5 * day)},
+		}
+
+		tracker := &fakeTracker{}
+		changed, err := sync(tracker).Sync(occurrences, tracked)
+		expect(err).To(matchers.BeNil())
+		expect(tracker.opened).To(matchers.HaveLen(2))
+		expect(tracker.comments).To(matchers.Equal([]string{"10"}))
+		expect(tracker.closed).To(matchers.Equal([]string{"12"}))
+
+		expect(changed).To(matchers.HaveLen(4))
+		expect(changed[0].Key).To(matchers.Equal("10"))
+		expect(changed[0].Occurrences).To(matchers.Equal(3))
+		expect(changed[1].State).To(matchers.Equal(IssueClosed))
+		expect(changed[2].Key).To(matchers.Equal("2"))
+		expect(changed[2].State).To(matchers.Equal(IssueOpen))
+		expect(changed[3].Key).To(matchers.Equal("1"))
+		expect(changed[3].URL).To(matchers.Equal("https://issues.example.com/1"))
+		expect(changed[3].Tracker).To(matchers.Equal("fake"))
+	})
+
+	o.Spec("files issues through the GitHub API", func(expect expect.Expectation) {
+		var requests []string
+		var issue map[string]interface{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.Method+" "+r.URL.Path+" "+r.Header.Get("Authorization"))
+			if r.URL.Path == "/repos/owner/name/issues" {
+				_ = json.NewDecoder(r.Body).Decode(&issue)
+				_, _ = w.Write([]byte(`{"number": 7, "html_url": "https://github.com/owner/name/issues/7"}`))
+			}
+		}))
+		defer srv.Close()
+
+		tracker := GitHubTracker{URL: srv.URL, Repo: "owner/name", Token: "secret"}
+		key, url, err := tracker.Open("gocop", FlakyOccurrence{
+			Package: "example.com/flaky", Test: "TestFlaky", Runs: 2, FirstSha: "abc", Output: []string{"flaky_test.go:3: boom"},
+		})
+		expect(err).To(matchers.BeNil())
+		expect(key).To(matchers.Equal("7"))
+		expect(url).To(matchers.Equal("https://github.com/owner/name/issues/7"))
+		expect(issue["title"]).To(matchers.Equal("Flaky test TestFlaky in example.com/flaky"))
+		expect(issue["body"]).To(matchers.ContainSubstring("First seen at abc."))
+		expect(issue["body"]).To(matchers.ContainSubstring("```\nflaky_test.go:3: boom\n```"))
+
+		err = tracker.Close(key, "stable")
+		expect(err).To(matchers.BeNil())
+		expect(requests).To(matchers.Equal([]string{
+			"POST /repos/owner/name/issues Bearer secret",
+			"POST /repos/owner/name/issues/7/comments Bearer secret",
+			"PATCH /repos/owner/name/issues/7 Bearer secret",
+		}))
+	})
+
+	o.Spec("closes Jira issues through their transition", func(expect expect.Expectation) {
+		var transition map[string]map[string]string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/rest/api/2/issue/GO-1/transitions" {
+				return
+			}
+			if r.Method == http.MethodGet {
+				_, _ = w.Write([]byte(`{"transitions": [{"id": "11", "name": "In Progress"}, {"id": "31", "name": "Done"}]}`))
+				return
+			}
+			_ = json.NewDecoder(r.Body).Decode(&transition)
+		}))
+		defer srv.Close()
+
+		err := JiraTracker{URL: srv.URL, CloseTransition: "done"}.Close("GO-1", "stable")
+		expect(err).To(matchers.BeNil())
+		expect(transition["transition"]["id"]).To(matchers.Equal("31"))
+
+		err = JiraTracker{URL: srv.URL, CloseTransition: "Resolved"}.Close("GO-1", "stable")
+		expect(err).To(matchers.Not(matchers.BeNil()))
+	})
+}
@@ -0,0 +1,204 @@
+package gocop
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// JUnitTestSuites is the root element of a JUnit XML report
+type JUnitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []JUnitTestSuite `xml:"testsuite"`
+}
+
+// JUnitTestSuite reports the tests of a single package
+type JUnitTestSuite struct {
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Errors     int              `xml:"errors,attr"`
+	Skipped    int              `xml:"skipped,attr"`
+	Time       string           `xml:"time,attr"`
+	Timestamp  string           `xml:"timestamp,attr,omitempty"`
+	Properties *JUnitProperties `xml:"properties,omitempty"`
+	TestCases  []JUnitTestCase  `xml:"testcase"`
+	SystemOut  *JUnitOutput     `xml:"system-out,omitempty"`
+}
+
+// JUnitTestCase reports the outcome of a single test
+type JUnitTestCase struct {
+	Classname  string           `xml:"classname,attr"`
+	Name       string           `xml:"name,attr"`
+	Time       string           `xml:"time,attr"`
+	Properties *JUnitProperties `xml:"properties,omitempty"`
+	Failure    *JUnitMessage    `xml:"failure,omitempty"`
+	Error      *JUnitMessage    `xml:"error,omitempty"`
+	Skipped    *JUnitMessage    `xml:"skipped,omitempty"`
+	SystemOut  *JUnitOutput     `xml:"system-out,omitempty"`
+}
+
+// JUnitMessage describes why a test failed, errored or was skipped
+type JUnitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Body    string `xml:",cdata"`
+}
+
+// JUnitOutput contains output captured from a test suite or test case
+type JUnitOutput struct {
+	Data string `xml:",cdata"`
+}
+
+// JUnitProperties annotate a test suite or test case
+type JUnitProperties struct {
+	Property []JUnitProperty `xml:"property"`
+}
+
+// JUnitProperty annotates a test suite or test case
+type JUnitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// NewJUnit converts package results into a JUnit XML report, annotating tests found in flaky
+func NewJUnit(results PackageResults, flaky FlakyPackages) JUnitTestSuites {
+	flakyPkgs := make(map[string]FlakyPackage)
+	for _, pkg := range flaky {
+		flakyPkgs[pkg.Package] = pkg
+	}
+
+	var report JUnitTestSuites
+	var elapsed float64
+	for _, result := range results {
+		suite := newJUnitTestSuite(result, flakyPkgs[result.Package])
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Skipped += suite.Skipped
+		report.Suites = append(report.Suites, suite)
+		elapsed += result.Duration
+	}
+	report.Time = junitTime(elapsed)
+
+	return report
+}
+
+func newJUnitTestSuite(result PackageResult, flaky FlakyPackage) JUnitTestSuite {
+	suite := JUnitTestSuite{
+		Name: result.Package,
+		Time: junitTime(result.Duration),
+	}
+	output := strings.Join(result.Output, "\n")
+	if len(output) > 0 {
+		suite.SystemOut = &JUnitOutput{Data: output}
+	}
+
+	properties := make([]JUnitProperty, 0)
+	if result.Coverage > 0 {
+		properties = append(properties, JUnitProperty{Name: "coverage", Value: strconv.FormatFloat(result.Coverage, 'f', -1, 64)})
+	}
+	if flaky.Package != "" {
+		properties = append(properties, flakyProperties(flaky.Attempts, flaky.Failures)...)
+	}
+	if len(properties) > 0 {
+		suite.Properties = &JUnitProperties{Property: properties}
+	}
+
+	flakyTests := make(map[string]FlakyTest)
+	for _, test := range flaky.Tests {
+		flakyTests[test.Name] = test
+	}
+
+	failed := false
+	for _, test := range result.Tests {
+		tc := JUnitTestCase{
+			Classname: result.Package,
+			Name:      test.Name,
+			Time:      junitTime(test.Duration),
+		}
+		if ft, ok := flakyTests[test.Name]; ok {
+			tc.Properties = &JUnitProperties{Property: flakyProperties(ft.Attempts, ft.Failures)}
+		}
+
+		switch test.Outcome {
+		case ResultFail:
+			failed = true
+			suite.Failures++
+			tc.Failure = junitMessage(test.Output, "Failed")
+		case ResultSkip:
+			suite.Skipped++
+			tc.Skipped = junitMessage(test.Output, "Skipped")
+		}
+
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+
+	// failures outside of any test, such as builds, panics and timeouts, are reported for the package itself
+	if result.Outcome == ResultFail && !failed {
+		tc := JUnitTestCase{Classname: result.Package, Time: junitTime(result.Duration)}
+		switch result.Status {
+		case StatusBuildFailed, StatusSetupFailed:
+			suite.Errors++
+			tc.Name = "[" + result.Status + "]"
+			tc.Error = &JUnitMessage{Message: result.Status, Body: output}
+		default:
+			suite.Failures++
+			tc.Name = "[package]"
+			tc.Failure = &JUnitMessage{Message: "package failed", Body: output}
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	suite.Tests = len(suite.TestCases)
+
+	return suite
+}
+
+// junitMessage uses the first line logged by a test as the message, falling back to a default
+func junitMessage(output []string, fallback string) *JUnitMessage {
+	msg := &JUnitMessage{Message: fallback, Body: strings.Join(output, "\n")}
+	for _, line := range output {
+		if !isMarker(line) && strings.TrimSpace(line) != "" {
+			msg.Message = strings.TrimSpace(line)
+			break
+		}
+	}
+
+	return msg
+}
+
+func flakyProperties(attempts, failures int) []JUnitProperty {
+	return []JUnitProperty{
+		{Name: "flaky", Value: "true"},
+		{Name: "attempts", Value: strconv.Itoa(attempts)},
+		{Name: "failures", Value: strconv.Itoa(failures)},
+	}
+}
+
+func junitTime(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', 3, 64)
+}
+
+// WriteJUnit writes a JUnit XML report to w
+func WriteJUnit(w io.Writer, report JUnitTestSuites) error {
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	err = enc.Encode(report)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,36 @@
+package gocop_test
+
+import (
+	"testing"
+
+	"github.com/digitalocean/gocop/gocop"
+	"github.com/poy/onpar"
+	"github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestNewJUnit(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	o.Spec("reports test failures, build errors and flaky tests", func(expect expect.Expectation) {
+		results := gocop.ParseFileResults("testdata/run0.txt")
+		flaky := gocop.FlakyFileReport("testdata/run0.txt", "testdata/run1.txt")
+		report := gocop.NewJUnit(results, flaky)
+
+		expect(report.Tests).To(Equal(3))
+		expect(report.Failures).To(Equal(2))
+		expect(report.Errors).To(Equal(1))
+		expect(report.Suites[1].TestCases[0].Name).To(Equal("[build failed]"))
+		expect(report.Suites[2].TestCases[0].Name).To(Equal("TestMightFail"))
+		expect(report.Suites[2].TestCases[0].Properties.Property).To(Equal([]gocop.JUnitProperty{
+			{Name: "flaky", Value: "true"},
+			{Name: "attempts", Value: "2"},
+			{Name: "failures", Value: "1"},
+		}))
+	})
+}
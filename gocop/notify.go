@@ -0,0 +1,202 @@
+package gocop
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	// NotifyFailing announces a package which started failing
+	NotifyFailing = "failing"
+	// NotifyRecovered announces a failing package which passed again
+	NotifyRecovered = "recovered"
+	// NotifyFlaky announces a package newly detected as flaky
+	NotifyFlaky = "flaky"
+)
+
+const (
+	// WebhookGeneric posts notifications as JSON
+	WebhookGeneric = "generic"
+	// WebhookSlack posts a Slack compatible message
+	WebhookSlack = "slack"
+	// WebhookTeams posts a Microsoft Teams message card
+	WebhookTeams = "teams"
+)
+
+// Notification reports a change in the health of a package on a branch
+type Notification struct {
+	Kind    string    `json:"kind"`
+	Repo    string    `json:"repo"`
+	Branch  string    `json:"branch"`
+	Sha     string    `json:"sha"`
+	BuildID int64     `json:"build_id"`
+	Package string    `json:"package"`
+	Message string    `json:"message,omitempty"`
+	Owners  []string  `json:"owners,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+// Announcement records the last failing or flaky notification sent for a package, so it is not repeated
+type Announcement struct {
+	Package string
+	Kind    string
+	Created time.Time
+}
+
+// Webhook is an endpoint notifications are posted to
+type Webhook struct {
+	Kind string
+	URL  string
+}
+
+// DefaultNotifyTemplate renders the text of the message posted for notifications
+const DefaultNotifyTemplate = `{{range .}}{{if eq .Kind "failing"}}:x: ` + "`{{.Package}}`" + ` started failing{{else if eq .Kind "recovered"}}:white_check_mark: ` + "`{{.Package}}`" + ` recovered{{else}}:warning: ` + "`{{.Package}}`" + ` is flaky{{end}} on {{.Repo}} {{.Branch}}{{if .Sha}} at {{short .Sha}}{{end}}{{if .Message}}: {{.Message}}{{end}}{{if .Owners}} cc {{join .Owners " "}}{{end}}
+{{end}}`
+
+// NewNotifications compares the results of a run with what was already announced for its branch
+//
+// A failing package is announced once until it recovers, and a flaky package at most once per window.
+// Notifications are grouped by the first owner of their package when owners were resolved.
+func NewNotifications(run TestRun, results PackageResults, flaky FlakyPackages, announced []Announcement, window time.Duration) []Notification {
+	last := make(map[string]Announcement)
+	for _, a := range announced {
+		last[a.Kind+"\x00"+a.Package] = a
+	}
+
+	notification := func(kind, pkg, message string, owners []string) Notification {
+		return Notification{
+			Kind:    kind,
+			Repo:    run.Repo,
+			Branch:  run.Branch,
+			Sha:     run.Sha,
+			BuildID: run.BuildID,
+			Package: pkg,
+			Message: message,
+			Owners:  owners,
+			Created: run.Created,
+		}
+	}
+
+	isFlaky := make(map[string]bool)
+	for _, pkg := range flaky {
+		isFlaky[pkg.Package] = true
+	}
+
+	notifications := make([]Notification, 0)
+	for _, result := range results {
+		_, failing := last[NotifyFailing+"\x00"+result.Package]
+		switch {
+		case result.Outcome == ResultFail && !failing && !isFlaky[result.Package]:
+			notifications = append(notifications, notification(NotifyFailing, result.Package, failureMessage(result), result.Owners))
+		case result.Outcome == ResultPass && failing:
+			notifications = append(notifications, notification(NotifyRecovered, result.Package, "", result.Owners))
+		}
+	}
+
+	for _, pkg := range flaky {
+		a, ok := last[NotifyFlaky+"\x00"+pkg.Package]
+		if ok && run.Created.Sub(a.Created) < window {
+			continue
+		}
+		notifications = append(notifications, notification(NotifyFlaky, pkg.Package, fmt.Sprintf("failed %d of %d attempts", pkg.Failures, pkg.Attempts), pkg.Owners))
+	}
+
+	sort.SliceStable(notifications, func(i, j int) bool {
+		a, b := notifications[i].Owners, notifications[j].Owners
+		if len(a) == 0 || len(b) == 0 {
+			return len(a) > 0 && len(b) == 0
+		}
+		return a[0] < b[0]
+	})
+	return notifications
+}
+
+// failureMessage summarizes why a package failed in a single line
+func failureMessage(result PackageResult) string {
+	for _, test := range result.Tests {
+		if test.Outcome == ResultFail {
+			return test.Name + " " + junitMessage(test.Output, "failed").Message
+		}
+	}
+	return junitMessage(result.Output, statusOr(result.Status, "failed")).Message
+}
+
+// ParseWebhook parses a webhook given as kind=url, or a url for a generic webhook
+func ParseWebhook(s string) (Webhook, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) == 1 || strings.Contains(parts[0], "/") {
+		return Webhook{Kind: WebhookGeneric, URL: s}, nil
+	}
+
+	switch parts[0] {
+	case WebhookGeneric, WebhookSlack, WebhookTeams:
+		return Webhook{Kind: parts[0], URL: parts[1]}, nil
+	}
+	return Webhook{}, fmt.Errorf("unknown webhook kind %q, expected one of %s|%s|%s", parts[0], WebhookGeneric, WebhookSlack, WebhookTeams)
+}
+
+// NewNotifyTemplate parses the template rendering the text of notifications
+func NewNotifyTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultNotifyTemplate
+	}
+	return template.New("notify").Funcs(template.FuncMap{
+		"short": func(sha string) string {
+			if len(sha) > 10 {
+				return sha[:10]
+			}
+			return sha
+		},
+		"join": strings.Join,
+	}).Parse(text)
+}
+
+// Send posts notifications to the webhook, rendering their text with tmpl
+func (w Webhook) Send(notifications []Notification, tmpl *template.Template) error {
+	var text bytes.Buffer
+	err := tmpl.Execute(&text, notifications)
+	if err != nil {
+		return err
+	}
+
+	var payload interface{}
+	switch w.Kind {
+	case WebhookSlack:
+		payload = map[string]interface{}{"text": text.String()}
+	case WebhookTeams:
+		payload = map[string]interface{}{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  fmt.Sprintf("gocop: %d test health changes", len(notifications)),
+			"text":     text.String(),
+		}
+	default:
+		payload = map[string]interface{}{"text": text.String(), "notifications": notifications}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	client := http.Client{Timeout: 30 * time.Second}
+	res, err := client.Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		content, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("posting to %s webhook failed: %s: %s", w.Kind, res.Status, bytes.TrimSpace(content))
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package gocop
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/poy/onpar"
+	"github.com/poy/onpar/expect"
+	"github.com/poy/onpar/matchers"
+)
+
+func TestNotifications(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	run := TestRun{Repo: "gocop", Branch: "master", Sha: "0123456789abcdef", Created: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)}
+	results := PackageResults{
+		{Package: "example.com/new", Outcome: ResultFail, Tests: []TestCase{{Name: "TestNew", Outcome: ResultFail, Output: []string{"new_test.go:3: boom"}}}},
+		{Package: "example.com/broken", Outcome: ResultFail},
+		{Package: "example.com/fixed", Outcome: ResultPass},
+		{Package: "example.com/flaky", Outcome: ResultFail},
+		{Package: "example.com/known", Outcome: ResultFail},
+	}
+	flaky := FlakyPackages{
+		{Package: "example.com/flaky", Attempts: 3, Failures: 1},
+		{Package: "example.com/known", Attempts: 3, Failures: 1},
+	}
+	announced := []Announcement{
+		{Package: "example.com/broken", Kind: NotifyFailing},
+		{Package: "example.com/fixed", Kind: NotifyFailing},
+		{Package: "example.com/known", Kind: NotifyFlaky, Created: run.Created.Add(-time.Hour)},
+	}
+
+	o.Spec("announces only changes in health", func(expect expect.Expectation) {
+		notifications := NewNotifications(run, results, flaky, announced, 24*time.Hour)
+		expect(notifications).To(matchers.HaveLen(3))
+		expect(notifications[0].Kind).To(matchers.Equal(NotifyFailing))
+		expect(notifications[0].Package).To(matchers.Equal("example.com/new"))
+		expect(notifications[0].Message).To(matchers.Equal("TestNew new_test.go:3: boom"))
+		expect(notifications[1].Kind).To(matchers.Equal(NotifyRecovered))
+		expect(notifications[2].Kind).To(matchers.Equal(NotifyFlaky))
+		expect(notifications[2].Package).To(matchers.Equal("example.com/flaky"))
+	})
+
+	o.Spec("announces flaky packages again after the window", func(expect expect.Expectation) {
+		notifications := NewNotifications(run, nil, flaky, announced, time.Minute)
+		expect(notifications).To(matchers.HaveLen(2))
+	})
+
+	o.Spec("groups notifications by owner", func(expect expect.Expectation) {
+		owned := PackageResults{
+			{Package: "example.com/a", Outcome: ResultFail},
+			{Package: "example.com/b", Outcome: ResultFail, Owners: []string{"@org/b"}},
+			{Package: "example.com/c", Outcome: ResultFail, Owners: []string{"@org/a"}},
+		}
+		notifications := NewNotifications(run, owned, nil, nil, 0)
+		expect(notifications).To(matchers.HaveLen(3))
+		expect(notifications[0].Package).To(matchers.Equal("example.com/c"))
+		expect(notifications[1].Package).To(matchers.Equal("example.com/b"))
+		expect(notifications[2].Package).To(matchers.Equal("example.com/a"))
+
+		tmpl, err := NewNotifyTemplate("")
+		expect(err).To(matchers.BeNil())
+		var text strings.Builder
+		expect(tmpl.Execute(&text, notifications[:1])).To(matchers.BeNil())
+		expect(text.String()).To(matchers.Equal(":x: `example.com/c` started failing on gocop master at 0123456789: failed cc @org/a\n"))
+	})
+
+	o.Spec("parses webhooks", func(expect expect.Expectation) {
+		hook, err := ParseWebhook("slack=https://hooks.slack.com/services/x")
+		expect(err).To(matchers.BeNil())
+		expect(hook).To(matchers.Equal(Webhook{Kind: WebhookSlack, URL: "https://hooks.slack.com/services/x"}))
+
+		hook, err = ParseWebhook("https://example.com/hook?a=b")
+		expect(err).To(matchers.BeNil())
+		expect(hook.Kind).To(matchers.Equal(WebhookGeneric))
+
+		_, err = ParseWebhook("irc=https://example.com")
+		expect(err).To(matchers.Not(matchers.BeNil()))
+	})
+
+	o.Spec("posts the rendered text to webhooks", func(expect expect.Expectation) {
+		var payload map[string]interface{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+		}))
+		defer srv.Close()
+
+		tmpl, err := NewNotifyTemplate("")
+		expect(err).To(matchers.BeNil())
+
+		notifications := NewNotifications(run, results[:1], nil, nil, 0)
+		err = Webhook{Kind: WebhookTeams, URL: srv.URL}.Send(notifications, tmpl)
+		expect(err).To(matchers.BeNil())
+		expect(payload["@type"]).To(matchers.Equal("MessageCard"))
+		expect(payload["text"]).To(matchers.Equal(":x: `example.com/new` started failing on gocop master at 0123456789: TestNew new_test.go:3: boom\n"))
+
+		err = Webhook{Kind: WebhookGeneric, URL: srv.URL}.Send(notifications, tmpl)
+		expect(err).To(matchers.BeNil())
+		expect(payload["notifications"]).To(matchers.HaveLen(1))
+	})
+}
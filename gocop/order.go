@@ -0,0 +1,240 @@
+package gocop
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotReproduced reports tests which passed when replaying the order of a failed -shuffle seed
+var ErrNotReproduced = errors.New("the failure did not reproduce when replaying the test order")
+
+// OrderSearch replays the test order of a failed run with -shuffle, then delta-debugs the tests which ran before
+// a failed test to find the polluters it fails after
+//
+// Shuffling orders all tests of the binary before -test.run selects some of them, so running a subset of the
+// tests with the same seed keeps their order of the failed run.
+type OrderSearch struct {
+	Binary *TestBinary
+	Seed   int64
+	// Victim is the failed test to find the polluters of, the first test failing in the replay unless set
+	Victim string
+	// Args are passed to every run of the binary
+	Args []string
+	// Runs repeats each run until it fails, finding polluters which only sometimes fail the victim
+	Runs    int
+	Timeout time.Duration
+}
+
+// OrderReport is the outcome of an OrderSearch
+type OrderReport struct {
+	Package string `json:"package"`
+	Seed    int64  `json:"seed"`
+	// Order lists the tests in the order they ran in the replay
+	Order     []string `json:"order"`
+	Victim    string   `json:"victim"`
+	Polluters []string `json:"polluters"`
+	// Alone tells the victim also failed without any other test running before it
+	Alone bool `json:"alone"`
+	// Runs counts the runs of the binary made by the search
+	Runs    int           `json:"runs"`
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// Run replays the seed and searches the polluters of the victim
+func (s OrderSearch) Run() (report OrderReport, err error) {
+	report = OrderReport{Package: s.Binary.Package, Seed: s.Seed, Order: make([]string, 0), Polluters: make([]string, 0)}
+	start := time.Now()
+	defer func() {
+		report.Elapsed = time.Since(start)
+	}()
+
+	// the replay is verbose to learn the order the tests ran in
+	var out []byte
+	failed, err := s.repeat(&report, func() (bool, error) {
+		var passed bool
+		var err error
+		passed, out, err = s.Binary.Run(append(s.args(), "-test.v"), nil, s.Timeout)
+		return !passed && (s.Victim == "" || failedTest(out, s.Victim)), err
+	})
+	if err != nil {
+		return report, err
+	}
+	if !failed {
+		return report, ErrNotReproduced
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		if m := testRunRe.FindStringSubmatch(line); m != nil && !strings.Contains(m[2], "/") {
+			report.Order = appendNew(report.Order, m[2])
+		}
+		if m := failRe.FindStringSubmatch(line); m != nil && report.Victim == "" && !strings.Contains(m[1], "/") {
+			report.Victim = m[1]
+		}
+	}
+	if s.Victim != "" {
+		report.Victim = s.Victim
+	}
+	if report.Victim == "" {
+		return report, errors.New("the replay failed without a failed test: " + FailureFingerprint(strings.Split(string(out), "\n")))
+	}
+
+	before := make([]string, 0)
+	for _, test := range report.Order {
+		if test == report.Victim {
+			break
+		}
+		before = append(before, test)
+	}
+
+	report.Alone, err = s.fails(&report, nil)
+	if err != nil || report.Alone {
+		return report, err
+	}
+
+	failed, err = s.fails(&report, before)
+	if err != nil {
+		return report, err
+	}
+	if !failed {
+		// the victim rather depends on tests running after it, or alongside it with t.Parallel
+		return report, ErrNotReproduced
+	}
+
+	report.Polluters, err = minimize(before, func(tests []string) (bool, error) {
+		return s.fails(&report, tests)
+	})
+	return report, err
+}
+
+// fails reports whether the victim fails when running after the tests, in the order of the seed
+func (s OrderSearch) fails(report *OrderReport, tests []string) (bool, error) {
+	args := append(s.args(), "-test.run", testsPattern(append(append([]string{}, tests...), report.Victim)))
+	return s.repeat(report, func() (bool, error) {
+		passed, out, err := s.Binary.Run(args, nil, s.Timeout)
+		return !passed && failedTest(out, report.Victim), err
+	})
+}
+
+// repeat runs until a run fails, at most Runs times
+func (s OrderSearch) repeat(report *OrderReport, run func() (bool, error)) (bool, error) {
+	for i := 0; i < s.Runs || i == 0; i++ {
+		report.Runs++
+		failed, err := run()
+		if err != nil || failed {
+			return failed, err
+		}
+	}
+	return false, nil
+}
+
+func (s OrderSearch) args() []string {
+	return append(append([]string{}, s.Args...), "-test.shuffle", strconv.FormatInt(s.Seed, 10))
+}
+
+// minimize delta-debugs tests down to a minimal subset which still fails, keeping their order
+func minimize(tests []string, fails func([]string) (bool, error)) ([]string, error) {
+	n := 2
+	for len(tests) >= 2 {
+		chunks := split(tests, n)
+		reduced := false
+
+		for _, chunk := range chunks {
+			failed, err := fails(chunk)
+			if err != nil {
+				return tests, err
+			}
+			if failed {
+				tests, n, reduced = chunk, 2, true
+				break
+			}
+		}
+
+		// with two chunks the complement of one is the other, which was tried already
+		for i := 0; !reduced && n > 2 && i < len(chunks); i++ {
+			complement := make([]string, 0)
+			for j, chunk := range chunks {
+				if j != i {
+					complement = append(complement, chunk...)
+				}
+			}
+
+			failed, err := fails(complement)
+			if err != nil {
+				return tests, err
+			}
+			if failed {
+				tests, n, reduced = complement, n-1, true
+			}
+		}
+
+		if !reduced {
+			if n >= len(tests) {
+				break
+			}
+			n *= 2
+			if n > len(tests) {
+				n = len(tests)
+			}
+		}
+	}
+
+	return tests, nil
+}
+
+// split divides tests into n contiguous chunks of nearly equal length
+func split(tests []string, n int) [][]string {
+	chunks := make([][]string, 0, n)
+	start := 0
+	for i := 0; i < n; i++ {
+		end := start + (len(tests)-start)/(n-i)
+		chunks = append(chunks, tests[start:end])
+		start = end
+	}
+	return chunks
+}
+
+// testsPattern is the -run pattern matching exactly the top-level tests
+func testsPattern(tests []string) string {
+	quoted := make([]string, 0, len(tests))
+	for _, test := range tests {
+		quoted = append(quoted, regexp.QuoteMeta(test))
+	}
+	return "^(" + strings.Join(quoted, "|") + ")$"
+}
+
+// failedTest reports whether output shows a test, or one of its subtests, failed
+func failedTest(out []byte, test string) bool {
+	for _, line := range strings.Split(string(out), "\n") {
+		if m := failRe.FindStringSubmatch(line); m != nil && (m[1] == test || strings.HasPrefix(m[1], test+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Text describes the polluters found, with a command reproducing the failure
+func (r OrderReport) Text() string {
+	if r.Alone {
+		return fmt.Sprintf("%s in %s fails without other tests running before it with -shuffle %d, so it does not depend on the test order",
+			r.Victim, r.Package, r.Seed)
+	}
+
+	text := fmt.Sprintf("%s in %s fails after %s with -shuffle %d, found in %d runs over %s",
+		r.Victim, r.Package, strings.Join(r.Polluters, ", "), r.Seed, r.Runs, r.Elapsed.Round(time.Millisecond))
+	text += fmt.Sprintf("\n  go test -run '%s' -shuffle %d %s", testsPattern(append(append([]string{}, r.Polluters...), r.Victim)), r.Seed, r.Package)
+	return text
+}
+
+// Records lists the polluters of the victim with a header row
+func (r OrderReport) Records() [][]string {
+	seed := strconv.FormatInt(r.Seed, 10)
+	records := [][]string{{"package", "seed", "victim", "polluter"}}
+	for _, polluter := range r.Polluters {
+		records = append(records, []string{r.Package, seed, r.Victim, polluter})
+	}
+	return records
+}
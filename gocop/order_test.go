@@ -0,0 +1,74 @@
+package gocop
+
+import (
+	"testing"
+
+	"github.com/poy/onpar"
+	"github.com/poy/onpar/expect"
+	"github.com/poy/onpar/matchers"
+)
+
+func TestMinimize(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	tests := []string{"TestA", "TestB", "TestC", "TestD", "TestE", "TestF", "TestG"}
+
+	o.Spec("finds a single polluter", func(expect expect.Expectation) {
+		polluters, err := minimize(tests, func(run []string) (bool, error) {
+			return contains(run, "TestE"), nil
+		})
+		expect(err).To(matchers.BeNil())
+		expect(polluters).To(matchers.Equal([]string{"TestE"}))
+	})
+
+	o.Spec("finds polluters which only fail the victim together, in order", func(expect expect.Expectation) {
+		polluters, err := minimize(tests, func(run []string) (bool, error) {
+			return contains(run, "TestB") && contains(run, "TestF"), nil
+		})
+		expect(err).To(matchers.BeNil())
+		expect(polluters).To(matchers.Equal([]string{"TestB", "TestF"}))
+	})
+
+	o.Spec("splits tests into contiguous chunks", func(expect expect.Expectation) {
+		expect(split(tests, 3)).To(matchers.Equal([][]string{{"TestA", "TestB"}, {"TestC", "TestD"}, {"TestE", "TestF", "TestG"}}))
+		expect(testsPattern([]string{"TestA", "TestB.c"})).To(matchers.Equal(`^(TestA|TestB\.c)$`))
+	})
+}
+
+func TestOrderSearch(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles test binaries")
+	}
+
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	o.Spec("finds the polluter of an order dependent test", func(expect expect.Expectation) {
+		binary, err := CompileTest(".", "github.com/digitalocean/gocop/sample/order", []string{"-tags", "sample"})
+		expect(err).To(matchers.BeNil())
+		defer binary.Remove()
+
+		// about half of the seeds run TestRegister before TestEmpty
+		var report OrderReport
+		for seed := int64(1); seed <= 20; seed++ {
+			report, err = OrderSearch{Binary: binary, Seed: seed, Runs: 1}.Run()
+			if err != ErrNotReproduced {
+				break
+			}
+		}
+		expect(err).To(matchers.BeNil())
+		expect(report.Victim).To(matchers.Equal("TestEmpty"))
+		expect(report.Polluters).To(matchers.Equal([]string{"TestRegister"}))
+		expect(report.Order).To(matchers.HaveLen(6))
+		expect(report.Alone).To(matchers.Equal(false))
+	})
+}
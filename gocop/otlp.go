@@ -0,0 +1,198 @@
+package gocop
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultOTLPEndpoint is the traces endpoint of an OTLP/HTTP collector running locally
+const DefaultOTLPEndpoint = "http://localhost:4318/v1/traces"
+
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusError      = 2
+)
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string     `json:"stringValue,omitempty"`
+	BoolValue   *bool       `json:"boolValue,omitempty"`
+	IntValue    string      `json:"intValue,omitempty"`
+	DoubleValue *float64    `json:"doubleValue,omitempty"`
+	ArrayValue  *otlpValues `json:"arrayValue,omitempty"`
+}
+
+type otlpValues struct {
+	Values []otlpValue `json:"values"`
+}
+
+// WriteOTLP writes a trace as the JSON encoding of an OTLP trace export request
+func WriteOTLP(w io.Writer, service string, root *Span) error {
+	traceID, err := randomID(16)
+	if err != nil {
+		return err
+	}
+
+	spans := make([]otlpSpan, 0)
+	err = appendOTLPSpans(&spans, traceID, "", root)
+	if err != nil {
+		return err
+	}
+
+	req := otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: otlpAttributes([]Attribute{{Key: "service.name", Value: service}})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "gocop"}, Spans: spans}},
+	}}}
+
+	return json.NewEncoder(w).Encode(req)
+}
+
+// ExportOTLP sends a trace to the traces endpoint of an OTLP/HTTP collector
+func ExportOTLP(endpoint string, headers map[string]string, service string, root *Span) error {
+	var body bytes.Buffer
+	err := WriteOTLP(&body, service, root)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	client := http.Client{Timeout: time.Minute}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		content, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("exporting trace to %s failed: %s: %s", endpoint, res.Status, bytes.TrimSpace(content))
+	}
+	return nil
+}
+
+func appendOTLPSpans(spans *[]otlpSpan, traceID, parentID string, span *Span) error {
+	spanID, err := randomID(8)
+	if err != nil {
+		return err
+	}
+
+	s := otlpSpan{
+		TraceID:           traceID,
+		SpanID:            spanID,
+		ParentSpanID:      parentID,
+		Name:              span.Name,
+		Kind:              otlpSpanKindInternal,
+		StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+		Attributes:        otlpAttributes(span.Attributes),
+	}
+	if span.Failed {
+		s.Status = &otlpStatus{Code: otlpStatusError, Message: span.Message}
+	}
+	*spans = append(*spans, s)
+
+	for _, child := range span.Children {
+		err = appendOTLPSpans(spans, traceID, spanID, child)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func otlpAttributes(attributes []Attribute) []otlpAttribute {
+	converted := make([]otlpAttribute, 0)
+	for _, a := range attributes {
+		converted = append(converted, otlpAttribute{Key: a.Key, Value: newOTLPValue(a.Value)})
+	}
+	return converted
+}
+
+func newOTLPValue(v interface{}) otlpValue {
+	switch v := v.(type) {
+	case bool:
+		return otlpValue{BoolValue: &v}
+	case int:
+		return otlpValue{IntValue: strconv.Itoa(v)}
+	case int64:
+		return otlpValue{IntValue: strconv.FormatInt(v, 10)}
+	case float64:
+		return otlpValue{DoubleValue: &v}
+	case []string:
+		values := make([]otlpValue, 0)
+		for _, s := range v {
+			values = append(values, newOTLPValue(s))
+		}
+		return otlpValue{ArrayValue: &otlpValues{Values: values}}
+	case string:
+		return otlpValue{StringValue: &v}
+	}
+
+	s := fmt.Sprint(v)
+	return otlpValue{StringValue: &s}
+}
+
+func randomID(n int) (string, error) {
+	id := make([]byte, n)
+	_, err := rand.Read(id)
+	return hex.EncodeToString(id), err
+}
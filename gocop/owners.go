@@ -0,0 +1,350 @@
+package gocop
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// CodeOwnersPaths lists where a CODEOWNERS file is looked up relative to the repository root
+var CodeOwnersPaths = []string{"CODEOWNERS", ".github/CODEOWNERS", ".gitlab/CODEOWNERS", "docs/CODEOWNERS"}
+
+var sectionRe = regexp.MustCompile(`^\^?\[([^\]]+)\](?:\[\d+\])?\s*(.*)$`)
+
+// CodeOwners maps paths to their owners following the rules of a CODEOWNERS file
+//
+// The last matching rule of each section wins. Rules of GitLab sections are combined, so a path has
+// the owners of every section with a matching rule.
+type CodeOwners struct {
+	sections [][]ownerRule
+}
+
+type ownerRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// ParseCodeOwners reads a CODEOWNERS file in GitHub or GitLab syntax
+func ParseCodeOwners(r io.Reader) (CodeOwners, error) {
+	var c CodeOwners
+	rules := make([]ownerRule, 0)
+	var defaults []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+
+		if m := sectionRe.FindStringSubmatch(line); m != nil {
+			if len(rules) > 0 {
+				c.sections = append(c.sections, rules)
+			}
+			rules = make([]ownerRule, 0)
+			defaults = strings.Fields(m[2])
+			continue
+		}
+
+		fields := strings.Fields(strings.Replace(line, `\ `, "\x00", -1))
+		pattern := strings.Replace(strings.Replace(fields[0], "\x00", " ", -1), `\#`, "#", -1)
+		owners := fields[1:]
+		if len(owners) == 0 {
+			owners = defaults
+		}
+
+		re, err := ownerPattern(pattern)
+		if err != nil {
+			return c, fmt.Errorf("invalid CODEOWNERS pattern %q: %v", pattern, err)
+		}
+		rules = append(rules, ownerRule{pattern: re, owners: owners})
+	}
+	if len(rules) > 0 {
+		c.sections = append(c.sections, rules)
+	}
+
+	return c, scanner.Err()
+}
+
+// stripComment removes a comment from a line, keeping escaped hashes
+func stripComment(line string) string {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '#' && (i == 0 || line[i-1] != '\\') {
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// ownerPattern converts a gitignore style pattern into a regular expression matching a path, and everything
+// below it for directories
+//
+// A pattern names a directory when it ends with / or its last segment has no wildcards, while a wildcard such as
+// docs/* only matches the entries directly in docs.
+func ownerPattern(pattern string) (*regexp.Regexp, error) {
+	dir := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	if !dir {
+		dir = !strings.ContainsAny(pattern[strings.LastIndex(pattern, "/")+1:], "*?[")
+	}
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	var re strings.Builder
+	re.WriteString("^")
+	if !anchored {
+		re.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			re.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if dir {
+		re.WriteString("(?:/.*)?")
+	}
+	re.WriteString("$")
+
+	return regexp.Compile(re.String())
+}
+
+// Owners lists the owners of a file or directory given relative to the repository root
+func (c CodeOwners) Owners(path string) []string {
+	path = strings.Trim(filepath.ToSlash(path), "/")
+	if path == "." {
+		path = ""
+	}
+
+	owners := make([]string, 0)
+	for _, rules := range c.sections {
+		for i := len(rules) - 1; i >= 0; i-- {
+			if rules[i].pattern.MatchString(path) {
+				owners = appendNew(owners, rules[i].owners...)
+				break
+			}
+		}
+	}
+
+	return owners
+}
+
+func appendNew(list []string, values ...string) []string {
+	for _, v := range values {
+		if !contains(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Ownership resolves the import paths of packages in a module to the owners of their directories
+type Ownership struct {
+	// Module is the module path from go.mod
+	Module string
+	// Dir is the directory of the module relative to the repository root
+	Dir        string
+	CodeOwners CodeOwners
+}
+
+// LoadOwnership finds the module containing dir and the CODEOWNERS file of its repository
+//
+// The repository root is the closest directory at or above the module with a CODEOWNERS file, unless
+// codeowners names the file to use.
+func LoadOwnership(dir, codeowners string) (*Ownership, error) {
+	moduleDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, err = os.Stat(filepath.Join(moduleDir, "go.mod")); err == nil {
+			break
+		}
+		parent := filepath.Dir(moduleDir)
+		if parent == moduleDir {
+			return nil, fmt.Errorf("no go.mod found in or above %s", dir)
+		}
+		moduleDir = parent
+	}
+
+	module, err := ModulePath(moduleDir)
+	if err != nil {
+		return nil, err
+	}
+
+	root := ""
+	if codeowners != "" {
+		root, err = repositoryRoot(moduleDir)
+	} else {
+		root, codeowners, err = findCodeOwners(moduleDir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(codeowners)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	owners, err := ParseCodeOwners(f)
+	if err != nil {
+		return nil, err
+	}
+
+	rel, err := filepath.Rel(root, moduleDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Ownership{Module: module, Dir: filepath.ToSlash(rel), CodeOwners: owners}, nil
+}
+
+// findCodeOwners looks for a CODEOWNERS file in dir and its parents
+func findCodeOwners(dir string) (string, string, error) {
+	for d := dir; ; {
+		for _, p := range CodeOwnersPaths {
+			file := filepath.Join(d, filepath.FromSlash(p))
+			if _, err := os.Stat(file); err == nil {
+				return d, file, nil
+			}
+		}
+
+		parent := filepath.Dir(d)
+		if parent == d {
+			return "", "", fmt.Errorf("no CODEOWNERS file found in or above %s", dir)
+		}
+		d = parent
+	}
+}
+
+// repositoryRoot finds the closest git work tree at or above dir, or dir itself without one
+func repositoryRoot(dir string) (string, error) {
+	for d := dir; ; {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d, nil
+		}
+
+		parent := filepath.Dir(d)
+		if parent == d {
+			return dir, nil
+		}
+		d = parent
+	}
+}
+
+// Owners lists the owners of a package, which has none outside the module
+func (o *Ownership) Owners(pkg string) []string {
+	var rel string
+	switch {
+	case pkg == o.Module:
+	case strings.HasPrefix(pkg, o.Module+"/"):
+		rel = strings.TrimPrefix(pkg, o.Module+"/")
+	default:
+		return []string{}
+	}
+
+	return o.CodeOwners.Owners(filepath.ToSlash(filepath.Join(o.Dir, rel)))
+}
+
+// Results sets the owners of each result
+func (o *Ownership) Results(results PackageResults) PackageResults {
+	owned := make(PackageResults, 0)
+	for _, result := range results {
+		result.Owners = o.Owners(result.Package)
+		owned = append(owned, result)
+	}
+	return owned
+}
+
+// Flaky sets the owners of each flaky package
+func (o *Ownership) Flaky(flaky FlakyPackages) FlakyPackages {
+	owned := make(FlakyPackages, 0)
+	for _, pkg := range flaky {
+		pkg.Owners = o.Owners(pkg.Package)
+		owned = append(owned, pkg)
+	}
+	return owned
+}
+
+// OwnerGroup lists the packages of an owner, the owner being empty for packages without one
+type OwnerGroup struct {
+	Owner    string   `json:"owner"`
+	Packages []string `json:"packages"`
+}
+
+// GroupByOwner groups packages by owner in order of owner, listing packages with several owners under each
+// and packages without owners last
+func GroupByOwner(packages []string, owners [][]string) []OwnerGroup {
+	groups := make([]OwnerGroup, 0)
+	index := make(map[string]int)
+	add := func(owner, pkg string) {
+		i, ok := index[owner]
+		if !ok {
+			i = len(groups)
+			index[owner] = i
+			groups = append(groups, OwnerGroup{Owner: owner})
+		}
+		if !contains(groups[i].Packages, pkg) {
+			groups[i].Packages = append(groups[i].Packages, pkg)
+		}
+	}
+
+	for i, pkg := range packages {
+		if len(owners[i]) == 0 {
+			add("", pkg)
+		}
+		for _, owner := range owners[i] {
+			add(owner, pkg)
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Owner == "" || groups[j].Owner == "" {
+			return groups[j].Owner == "" && groups[i].Owner != ""
+		}
+		return groups[i].Owner < groups[j].Owner
+	})
+	return groups
+}
+
+// ownerText lists packages under their owners, indenting packages below each owner
+func ownerText(groups []OwnerGroup) string {
+	lines := make([]string, 0)
+	for _, group := range groups {
+		owner := group.Owner
+		if owner == "" {
+			owner = "(unowned)"
+		}
+		lines = append(lines, owner)
+		for _, pkg := range group.Packages {
+			lines = append(lines, "  "+pkg)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
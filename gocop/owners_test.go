@@ -0,0 +1,124 @@
+package gocop
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/poy/onpar"
+	"github.com/poy/onpar/expect"
+	"github.com/poy/onpar/matchers"
+)
+
+const testCodeOwners = `# default owners
+*       @org/core
+
+/docs/          @org/docs
+cmd/            @org/cli
+/internal/**/db @org/storage @dba
+/api/*.go       @org/api
+/vendor/
+
+[Security][2] @org/security
+/auth/
+/internal/crypto/ @crypto # inline comment
+`
+
+func TestCodeOwners(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	owners, err := ParseCodeOwners(strings.NewReader(testCodeOwners))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o.Spec("uses the last matching rule", func(expect expect.Expectation) {
+		expect(owners.Owners("")).To(matchers.Equal([]string{"@org/core"}))
+		expect(owners.Owners("server")).To(matchers.Equal([]string{"@org/core"}))
+		expect(owners.Owners("docs/guide")).To(matchers.Equal([]string{"@org/docs"}))
+		expect(owners.Owners("vendor/github.com/lib/pq")).To(matchers.Equal([]string{}))
+	})
+
+	o.Spec("matches unanchored patterns at any depth", func(expect expect.Expectation) {
+		expect(owners.Owners("cmd")).To(matchers.Equal([]string{"@org/cli"}))
+		expect(owners.Owners("tools/cmd/gen")).To(matchers.Equal([]string{"@org/cli"}))
+		expect(owners.Owners("tools/cmdline")).To(matchers.Equal([]string{"@org/core"}))
+	})
+
+	o.Spec("matches wildcards", func(expect expect.Expectation) {
+		expect(owners.Owners("internal/db")).To(matchers.Equal([]string{"@org/storage", "@dba"}))
+		expect(owners.Owners("internal/store/db/migrate")).To(matchers.Equal([]string{"@org/storage", "@dba"}))
+		expect(owners.Owners("api/server.go")).To(matchers.Equal([]string{"@org/api"}))
+		expect(owners.Owners("api/v2/server.go")).To(matchers.Equal([]string{"@org/core"}))
+	})
+
+	o.Spec("matches only direct entries with wildcards in the last segment", func(expect expect.Expectation) {
+		nested, err := ParseCodeOwners(strings.NewReader("*  @org/core\n/docs/*  @org/docs\n/tools/**  @org/tools\n"))
+		expect(err).To(matchers.BeNil())
+		expect(nested.Owners("docs/a")).To(matchers.Equal([]string{"@org/docs"}))
+		expect(nested.Owners("docs/a/b")).To(matchers.Equal([]string{"@org/core"}))
+		expect(nested.Owners("tools/a/b")).To(matchers.Equal([]string{"@org/tools"}))
+	})
+
+	o.Spec("combines the owners of sections", func(expect expect.Expectation) {
+		expect(owners.Owners("auth/token")).To(matchers.Equal([]string{"@org/core", "@org/security"}))
+		expect(owners.Owners("internal/crypto")).To(matchers.Equal([]string{"@org/core", "@crypto"}))
+	})
+
+	o.Spec("resolves packages of a module in a subdirectory", func(expect expect.Expectation) {
+		codeOwners, err := ParseCodeOwners(strings.NewReader("/go/cmd/ @org/cli\n"))
+		expect(err).To(matchers.BeNil())
+		ownership := Ownership{Module: "example.com/svc", Dir: "go", CodeOwners: codeOwners}
+
+		expect(ownership.Owners("example.com/svc/cmd/svc")).To(matchers.Equal([]string{"@org/cli"}))
+		expect(ownership.Owners("example.com/svc")).To(matchers.Equal([]string{}))
+		expect(ownership.Owners("example.com/other/cmd")).To(matchers.Equal([]string{}))
+	})
+
+	o.Spec("groups packages by owner", func(expect expect.Expectation) {
+		results := PackageResults{
+			{Package: "example.com/a", Outcome: ResultFail, Owners: []string{"@b"}},
+			{Package: "example.com/b", Outcome: ResultFail, Owners: []string{}},
+			{Package: "example.com/c", Outcome: ResultFail, Owners: []string{"@a", "@b"}},
+		}
+		expect(results.Text()).To(matchers.Equal("@a\n  example.com/c\n@b\n  example.com/a\n  example.com/c\n(unowned)\n  example.com/b"))
+		expect(results.Records()[3]).To(matchers.Equal([]string{"example.com/c", ResultFail, "0", "0", "@a @b"}))
+
+		report := NewRunReport(results, FlakyPackages{{Package: "example.com/d", Owners: []string{"@a"}}}, nil)
+		expect(report.Owners).To(matchers.Equal([]OwnerGroup{
+			{Owner: "@a", Packages: []string{"example.com/c", "example.com/d"}},
+			{Owner: "@b", Packages: []string{"example.com/a", "example.com/c"}},
+			{Owner: "", Packages: []string{"example.com/b"}},
+		}))
+
+		markdown, err := report.Markdown()
+		expect(err).To(matchers.BeNil())
+		expect(markdown).To(matchers.ContainSubstring("### Owners\n\n- @a: `example.com/c`, `example.com/d`\n"))
+		expect(markdown).To(matchers.ContainSubstring("- unowned: `example.com/b`\n"))
+	})
+
+	o.Spec("loads the CODEOWNERS of the repository", func(expect expect.Expectation) {
+		root, err := ioutil.TempDir("", "gocop")
+		expect(err).To(matchers.BeNil())
+		defer os.RemoveAll(root)
+
+		module := filepath.Join(root, "go", "svc")
+		expect(os.MkdirAll(module, 0755)).To(matchers.BeNil())
+		expect(os.MkdirAll(filepath.Join(root, ".github"), 0755)).To(matchers.BeNil())
+		expect(ioutil.WriteFile(filepath.Join(module, "go.mod"), []byte("module example.com/svc\n"), 0644)).To(matchers.BeNil())
+		expect(ioutil.WriteFile(filepath.Join(root, ".github", "CODEOWNERS"), []byte("/go/svc/api/ @org/api\n"), 0644)).To(matchers.BeNil())
+
+		ownership, err := LoadOwnership(module, "")
+		expect(err).To(matchers.BeNil())
+		expect(ownership.Module).To(matchers.Equal("example.com/svc"))
+		expect(ownership.Dir).To(matchers.Equal("go/svc"))
+		expect(ownership.Owners("example.com/svc/api")).To(matchers.Equal([]string{"@org/api"}))
+	})
+}
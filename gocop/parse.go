@@ -1,69 +1,130 @@
 package gocop
 
 import (
-	"io/ioutil"
+	"bytes"
 	"log"
 	"regexp"
+	"strings"
 )
 
 const (
-	// ResultsPattern provides the REGEX pattern to find all package output
-	ResultsPattern = `((FAIL|ok|\?)\s+([\w\.\/\-]+)\s+([0-9s\.]+|\[build failed\]|\[no test files\])(\n|\s+coverage\:\s+([\d\.]+)\%\s+))`
+	// ResultsPattern provides the REGEX pattern to find package output on a single line
+	ResultsPattern = `^\s*(FAIL|ok|\?)\s+([\w\.\/\\\-~\+]+)\s+([0-9\.]+s|\(cached\)|\[build failed\]|\[setup failed\]|\[no test files\])(?:\s+\[[^\]]+\])?(?:\s+coverage:\s+(?:([\d\.]+)%)?.*)?\s*$`
+	// ResultLinePattern provides the REGEX pattern to find lines resembling package output
+	ResultLinePattern = `^\s*(FAIL|ok|\?)\s*\t`
+	// GotestsumPattern provides the REGEX pattern to find package output in gotestsum's pkgname formats
+	GotestsumPattern = `^\s*(✓|✖|∅)\s+([\w\.\/\\\-~\+]+)(?:\s+\(([0-9\.]+[a-zµ]*s|cached)\)|\s+\[(build failed|setup failed)\])?(?:\s+\(coverage:\s+(?:([\d\.]+)%)?[^)]*\))?\s*$`
+	// EscapePattern provides the REGEX pattern to find ANSI escape sequences
+	EscapePattern = "\x1b\\[[0-?]*[ -/]*[@-~]"
 )
 
-// Parse iterates over test output for all packages
-func Parse(output []byte) [][]string {
-	re := regexp.MustCompile(ResultsPattern)
-	matches := re.FindAllStringSubmatch(string(output), -1)
+var (
+	resultsRe    = regexp.MustCompile(ResultsPattern)
+	resultLineRe = regexp.MustCompile(ResultLinePattern)
+	gotestsumRe  = regexp.MustCompile(GotestsumPattern)
+	escapeRe     = regexp.MustCompile(EscapePattern)
+)
+
+// Normalize strips ANSI escape sequences and converts CRLF line endings to LF
+func Normalize(output []byte) []byte {
+	output = escapeRe.ReplaceAll(output, nil)
+	output = bytes.Replace(output, []byte("\r\n"), []byte("\n"), -1)
+	return bytes.Replace(output, []byte("\r"), []byte("\n"), -1)
+}
+
+// ParseLine parses a single line of package output, returning nil if the line holds no result
+func ParseLine(line []byte) []string {
+	match := resultsRe.FindSubmatch(line)
+	if match == nil {
+		if results := parseGotestsumLine(line); results != nil {
+			return results
+		}
+		if resultLineRe.Match(line) {
+			log.Printf("unable to parse result: %q", line)
+		}
+		return nil
+	}
 
-	packages := make([][]string, 0)
-	for _, match := range matches {
-		results := make([]string, 0)
+	return []string{
 		// outcome [0]
-		results = append(results, match[2])
+		string(match[1]),
 		// package [1]
-		results = append(results, match[3])
+		strings.Replace(string(match[2]), `\`, "/", -1),
 		// duration [2]
-		results = append(results, match[4])
+		string(match[3]),
 		// coverage [3]
-		results = append(results, match[6])
-		packages = append(packages, results)
+		string(match[4]),
 	}
+}
 
+// Parse iterates over test output for all packages
+func Parse(output []byte) [][]string {
+	packages, _ := ParseReader(bytes.NewReader(output))
 	return packages
 }
 
 // ParseFailed iterates over test output for failed packages
 func ParseFailed(output []byte) []string {
-	re := regexp.MustCompile(ResultsPattern)
-	matches := re.FindAllSubmatch(output, -1)
-
-	packages := make([]string, 0)
-	for _, match := range matches {
-		if string(match[2]) == "FAIL" {
-			packages = append(packages, string(match[3]))
-		}
-	}
-
+	packages, _ := ParseReaderFailed(bytes.NewReader(output))
 	return packages
 }
 
 // ParseFileFailed reads a file to Parse() failed packages
 func ParseFileFailed(path string) []string {
-	content, err := ioutil.ReadFile(path)
+	f, err := Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	packages, err := ParseReaderFailed(f)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	return ParseFailed(content)
+	return packages
 }
 
 // ParseFile reads a file to Parse() results
 func ParseFile(path string) [][]string {
-	content, err := ioutil.ReadFile(path)
+	f, err := Open(path)
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer f.Close()
 
-	return Parse(content)
+	packages, err := ParseReader(f)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return packages
+}
+
+// gotestsumOutcomes maps the symbols of gotestsum's pkgname formats to go test outcomes
+var gotestsumOutcomes = map[string]string{"✓": "ok", "✖": "FAIL", "∅": "?"}
+
+// parseGotestsumLine parses a line of gotestsum pkgname output into the same layout as ParseLine()
+func parseGotestsumLine(line []byte) []string {
+	match := gotestsumRe.FindSubmatch(line)
+	if match == nil {
+		return nil
+	}
+
+	duration := string(match[3])
+	switch {
+	case len(match[4]) > 0:
+		duration = "[" + string(match[4]) + "]"
+	case duration == "cached":
+		duration = "(cached)"
+	case duration == "":
+		duration = "[no test files]"
+	}
+
+	return []string{
+		gotestsumOutcomes[string(match[1])],
+		strings.Replace(string(match[2]), `\`, "/", -1),
+		duration,
+		string(match[5]),
+	}
 }
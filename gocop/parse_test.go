@@ -82,6 +82,31 @@ func TestParseFailed(t *testing.T) {
 			`),
 			want: []string{"github.com/digital-ocean/gocop/sample/hyphen"},
 		},
+		{
+			name:  "finds failed packages w/CRLF line endings",
+			input: []byte("FAIL\tgithub.com/digitalocean/gocop/sample/fail\t0.721s\r\nFAIL\tgithub.com/digitalocean/gocop/sample/failbuild [build failed]\r\nok  \tgithub.com/digitalocean/gocop/sample/pass\t0.250s\r\n"),
+			want:  []string{"github.com/digitalocean/gocop/sample/fail", "github.com/digitalocean/gocop/sample/failbuild"},
+		},
+		{
+			name:  "finds failed packages w/ANSI color codes",
+			input: []byte("\x1b[31mFAIL\x1b[0m\tgithub.com/digitalocean/gocop/sample/fail\t0.721s\n\x1b[32mok  \x1b[0m\tgithub.com/digitalocean/gocop/sample/pass\t0.250s\n"),
+			want:  []string{"github.com/digitalocean/gocop/sample/fail"},
+		},
+		{
+			name:  "finds failed package w/o trailing newline",
+			input: []byte("ok  \tgithub.com/digitalocean/gocop/sample/pass\t0.250s\nFAIL\tgithub.com/digitalocean/gocop/sample/fail\t0.721s"),
+			want:  []string{"github.com/digitalocean/gocop/sample/fail"},
+		},
+		{
+			name:  "finds failed package w/tilde and plus",
+			input: []byte("FAIL\texample.com/~user/c++/pkg\t0.721s\n"),
+			want:  []string{"example.com/~user/c++/pkg"},
+		},
+		{
+			name:  "finds failed package w/Windows path",
+			input: []byte("FAIL\t_\\C_\\Users\\gocop\\sample\\fail\t0.721s\r\n"),
+			want:  []string{"_/C_/Users/gocop/sample/fail"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -123,9 +148,34 @@ func TestParse(t *testing.T) {
 				{"ok", "github.com/digitalocean/gocop/sample/pass", "1.129s", "50.0"},
 			},
 		},
+		{
+			name: "finds cached and uncovered packages",
+			input: []byte(`
+				ok  	github.com/digitalocean/gocop/sample/pass	(cached)	coverage: 50.0% of statements
+				ok  	github.com/digitalocean/gocop/sample/numbers	0.012s	coverage: [no statements]
+				FAIL	github.com/digitalocean/gocop/sample/setup [setup failed]
+			`),
+			want: [][]string{{"ok", "github.com/digitalocean/gocop/sample/pass", "(cached)", "50.0"},
+				{"ok", "github.com/digitalocean/gocop/sample/numbers", "0.012s", ""},
+				{"FAIL", "github.com/digitalocean/gocop/sample/setup", "[setup failed]", ""},
+			},
+		},
+		{
+			name: "finds packages without tests to run",
+			input: []byte(`
+				ok  	github.com/digitalocean/gocop/sample/pass	0.005s [no tests to run]
+				ok  	github.com/digitalocean/gocop/sample/numbers	(cached) [no tests to run]
+				ok  	github.com/digitalocean/gocop/sample/flaky	0.004s	coverage: 0.0% of statements [no tests to run]
+			`),
+			want: [][]string{{"ok", "github.com/digitalocean/gocop/sample/pass", "0.005s", ""},
+				{"ok", "github.com/digitalocean/gocop/sample/numbers", "(cached)", ""},
+				{"ok", "github.com/digitalocean/gocop/sample/flaky", "0.004s", "0.0"},
+			},
+		},
 	}
 
 	for _, tt := range tests {
+		tt := tt
 		o.Spec(tt.name, func(expect expect.Expectation) {
 			got := Parse(tt.input)
 			expect(got).To(matchers.Equal(tt.want))
@@ -0,0 +1,244 @@
+package gocop
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ResultPass marks a package whose tests passed
+	ResultPass = "pass"
+	// ResultFail marks a package whose tests or build failed
+	ResultFail = "fail"
+	// ResultSkip marks a package without test files
+	ResultSkip = "skip"
+	// ResultFlaky marks a package which failed in some, but not all, attempts
+	ResultFlaky = "flaky"
+)
+
+// PackageResult contains the parsed outcome of a single package
+type PackageResult struct {
+	Package  string     `json:"package"`
+	Outcome  string     `json:"outcome"`
+	Duration float64    `json:"duration"`
+	Coverage float64    `json:"coverage"`
+	Status   string     `json:"status,omitempty"`
+	Tests    []TestCase `json:"tests,omitempty"`
+	Output   []string   `json:"output,omitempty"`
+	Owners   []string   `json:"owners,omitempty"`
+	// Shuffle is the seed of the order tests ran in with -shuffle, or 0 without
+	Shuffle int64 `json:"shuffle,omitempty"`
+}
+
+const (
+	// StatusBuildFailed marks a package which failed to build
+	StatusBuildFailed = "build failed"
+	// StatusSetupFailed marks a package which failed to set up
+	StatusSetupFailed = "setup failed"
+	// StatusNoTestFiles marks a package without test files
+	StatusNoTestFiles = "no test files"
+	// StatusCached marks a package whose result was reused from the build cache
+	StatusCached = "cached"
+)
+
+// TestCase contains the parsed outcome of a single test within a package
+type TestCase struct {
+	Name     string   `json:"name"`
+	Outcome  string   `json:"outcome"`
+	Duration float64  `json:"duration"`
+	Output   []string `json:"output,omitempty"`
+}
+
+// PackageResults lists the outcomes of packages in a test run
+type PackageResults []PackageResult
+
+// Text lists package names one per line, grouped under their owners when known
+func (p PackageResults) Text() string {
+	names := make([]string, 0)
+	owners := make([][]string, 0)
+	for _, result := range p {
+		names = append(names, result.Package)
+		owners = append(owners, result.Owners)
+	}
+
+	if p.owned() {
+		return ownerText(GroupByOwner(names, owners))
+	}
+	return strings.Join(names, "\n")
+}
+
+// Records lists package outcomes with a header row, and their owners when known
+func (p PackageResults) Records() [][]string {
+	owned := p.owned()
+	header := []string{"package", "outcome", "duration", "coverage"}
+	if owned {
+		header = append(header, "owners")
+	}
+
+	records := [][]string{header}
+	for _, result := range p {
+		record := []string{
+			result.Package,
+			result.Outcome,
+			strconv.FormatFloat(result.Duration, 'f', -1, 64),
+			strconv.FormatFloat(result.Coverage, 'f', -1, 64),
+		}
+		if owned {
+			record = append(record, strings.Join(result.Owners, " "))
+		}
+		records = append(records, record)
+	}
+
+	return records
+}
+
+// owned reports whether owners were resolved for any package
+func (p PackageResults) owned() bool {
+	for _, result := range p {
+		if result.Owners != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Outcome filters results to those with the given outcome
+func (p PackageResults) Outcome(outcome string) PackageResults {
+	results := make(PackageResults, 0)
+	for _, result := range p {
+		if result.Outcome == outcome {
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
+
+// NewPackageResult converts an entry from Parse() into a PackageResult
+func NewPackageResult(entry []string) (PackageResult, error) {
+	result := PackageResult{Package: entry[1]}
+	switch entry[0] {
+	case "ok":
+		result.Outcome = ResultPass
+	case "FAIL":
+		result.Outcome = ResultFail
+	case "?":
+		result.Outcome = ResultSkip
+	}
+
+	// build failures, missing test files and cached results carry a status instead of a duration
+	if strings.HasPrefix(entry[2], "[") || strings.HasPrefix(entry[2], "(") {
+		result.Status = strings.Trim(entry[2], "[]()")
+	} else {
+		d, err := time.ParseDuration(entry[2])
+		if err != nil {
+			return result, err
+		}
+		result.Duration = d.Seconds()
+	}
+
+	if entry[3] != "" {
+		f, err := strconv.ParseFloat(entry[3], 64)
+		if err != nil {
+			return result, err
+		}
+		result.Coverage = f
+	}
+
+	return result, nil
+}
+
+// TestResult converts a PackageResult for storage with a run created at the given time
+func (p PackageResult) TestResult(created time.Time) TestResult {
+	return TestResult{
+		Created:  created,
+		Package:  p.Package,
+		Result:   p.Outcome,
+		Duration: time.Duration(p.Duration * float64(time.Second)),
+		Coverage: p.Coverage / 100,
+		Shuffle:  p.Shuffle,
+	}
+}
+
+// Summary totals package outcomes across a test run
+type Summary struct {
+	Packages int     `json:"packages"`
+	Passed   int     `json:"passed"`
+	Failed   int     `json:"failed"`
+	Skipped  int     `json:"skipped"`
+	Duration float64 `json:"duration"`
+}
+
+// Summarize totals the outcomes of results
+func Summarize(results PackageResults) Summary {
+	var summary Summary
+	for _, result := range results {
+		summary.Packages++
+		summary.Duration += result.Duration
+		switch result.Outcome {
+		case ResultPass:
+			summary.Passed++
+		case ResultFail:
+			summary.Failed++
+		case ResultSkip:
+			summary.Skipped++
+		}
+	}
+
+	return summary
+}
+
+// Text describes the summary in a single line
+func (s Summary) Text() string {
+	return s.String()
+}
+
+// Records lists the summary totals with a header row
+func (s Summary) Records() [][]string {
+	return [][]string{
+		{"packages", "passed", "failed", "skipped", "duration"},
+		{
+			strconv.Itoa(s.Packages),
+			strconv.Itoa(s.Passed),
+			strconv.Itoa(s.Failed),
+			strconv.Itoa(s.Skipped),
+			strconv.FormatFloat(s.Duration, 'f', -1, 64),
+		},
+	}
+}
+
+func (s Summary) String() string {
+	return fmt.Sprintf("%d packages: %d passed, %d failed, %d skipped in %.3fs",
+		s.Packages, s.Passed, s.Failed, s.Skipped, s.Duration)
+}
+
+// ReadResults reads test output in any supported input format from r to convert each package result
+func ReadResults(r io.Reader) (PackageResults, error) {
+	results := make(PackageResults, 0)
+	err := EachResult(r, func(result PackageResult) error {
+		results = append(results, result)
+		return nil
+	})
+
+	return results, err
+}
+
+// ParseFileResults reads a file to convert each package result
+func ParseFileResults(path string) PackageResults {
+	f, err := Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	results, err := ReadResults(f)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return results
+}
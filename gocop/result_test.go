@@ -0,0 +1,76 @@
+package gocop
+
+import (
+	"testing"
+	"time"
+
+	"github.com/poy/onpar"
+	"github.com/poy/onpar/expect"
+	"github.com/poy/onpar/matchers"
+)
+
+func TestNewPackageResult(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	tests := []struct {
+		name  string
+		input []string
+		want  PackageResult
+	}{
+		{
+			name:  "converts passing package w/coverage",
+			input: []string{"ok", "github.com/digitalocean/gocop/sample/pass", "1.250s", "50.0"},
+			want:  PackageResult{Package: "github.com/digitalocean/gocop/sample/pass", Outcome: ResultPass, Duration: 1.25, Coverage: 50},
+		},
+		{
+			name:  "converts build failure w/o duration",
+			input: []string{"FAIL", "github.com/digitalocean/gocop/sample/failbuild", "[build failed]", ""},
+			want:  PackageResult{Package: "github.com/digitalocean/gocop/sample/failbuild", Outcome: ResultFail, Status: StatusBuildFailed},
+		},
+		{
+			name:  "converts package w/o test files",
+			input: []string{"?", "github.com/digitalocean/gocop/sample/numbers", "[no test files]", ""},
+			want:  PackageResult{Package: "github.com/digitalocean/gocop/sample/numbers", Outcome: ResultSkip, Status: StatusNoTestFiles},
+		},
+	}
+
+	for _, tt := range tests {
+		o.Spec(tt.name, func(expect expect.Expectation) {
+			got, err := NewPackageResult(tt.input)
+			expect(err).To(matchers.BeNil())
+			expect(got).To(matchers.Equal(tt.want))
+		})
+	}
+}
+
+func TestRunRecords(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	o.Spec("stores failed and flaky tests individually", func(expect expect.Expectation) {
+		created := time.Now()
+		results := PackageResults{{
+			Package: "example.com/fail",
+			Outcome: ResultFail,
+			Tests:   []TestCase{{Name: "TestPass", Outcome: ResultPass}, {Name: "TestFail", Outcome: ResultFail, Duration: 0.5, Output: []string{"boom"}}},
+		}}
+		flaky := FlakyPackages{{Package: "example.com/fail", Runs: results, Tests: []FlakyTest{{Name: "TestFail"}}}}
+
+		tests, cases := runRecords(created, results, flaky)
+		expect(tests).To(matchers.HaveLen(2))
+		expect(tests[1].Result).To(matchers.Equal(ResultFlaky))
+		expect(cases).To(matchers.Equal([]TestCaseResult{
+			{Created: created, Package: "example.com/fail", Name: "TestFail", Result: ResultFail, Duration: 500 * time.Millisecond, Output: []string{"boom"}},
+			{Created: created, Package: "example.com/fail", Name: "TestFail", Result: ResultFlaky, Output: []string{"boom"}},
+		}))
+	})
+}
@@ -0,0 +1,255 @@
+package gocop
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MaxLineLength limits how much of a single line of output is retained while scanning
+	MaxLineLength = 64 * 1024
+	// MaxOutputLines limits how many lines of output are retained for a single package
+	MaxOutputLines = 1000
+)
+
+const (
+	// TestPattern provides the REGEX pattern to find the outcome of a single test
+	TestPattern = `^\s*--- (PASS|FAIL|SKIP): (\S+) \(([\d\.]+)s\)`
+	// TestRunPattern provides the REGEX pattern to find a test starting or resuming in verbose output
+	TestRunPattern = `^=== (RUN|CONT|PAUSE|NAME)\s+(\S+)`
+	// BuildPattern provides the REGEX pattern to find the header of build output for a package
+	BuildPattern = `^# (\S+)`
+	// ShufflePattern provides the REGEX pattern to find the seed a test binary run with -shuffle ordered tests by
+	ShufflePattern = `^-test\.shuffle (-?\d+)$`
+)
+
+var (
+	testRe    = regexp.MustCompile(TestPattern)
+	testRunRe = regexp.MustCompile(TestRunPattern)
+	buildRe   = regexp.MustCompile(BuildPattern)
+	shuffleRe = regexp.MustCompile(ShufflePattern)
+)
+
+// testOutcomes maps test status in output to results
+var testOutcomes = map[string]string{"PASS": ResultPass, "FAIL": ResultFail, "SKIP": ResultSkip}
+
+// Scanner reads package results from test output one line at a time
+type Scanner struct {
+	r       *bufio.Reader
+	pending [][]byte
+	result  []string
+	err     error
+
+	// tests and output accumulate until the next package result
+	tests   []TestCase
+	current int
+	output  []string
+	lines   int
+	shuffle int64
+
+	// build output is collected per package until its result
+	build    map[string][]string
+	building string
+
+	resultTests   []TestCase
+	resultOutput  []string
+	resultShuffle int64
+}
+
+// NewScanner returns a Scanner reading test output from r
+func NewScanner(r io.Reader) *Scanner {
+	return &Scanner{
+		r:       bufio.NewReaderSize(r, MaxLineLength),
+		current: -1,
+		build:   make(map[string][]string),
+	}
+}
+
+// Scan advances to the next package result, returning false at the end of input or on error
+func (s *Scanner) Scan() bool {
+	for {
+		for len(s.pending) > 0 {
+			line := s.pending[0]
+			s.pending = s.pending[1:]
+			if s.scanLine(line) {
+				return true
+			}
+		}
+
+		if s.err != nil {
+			return false
+		}
+
+		line, err := s.readLine()
+		if err != nil {
+			s.err = err
+		}
+		if len(line) > 0 {
+			line = bytes.TrimSuffix(Normalize(line), []byte("\n"))
+			s.pending = bytes.Split(line, []byte("\n"))
+		}
+	}
+}
+
+// Result returns the most recent package result in the same layout as Parse()
+func (s *Scanner) Result() []string {
+	return s.result
+}
+
+// Tests returns the tests reported for the most recent package result
+func (s *Scanner) Tests() []TestCase {
+	return s.resultTests
+}
+
+// Output returns the output retained for the most recent package result
+func (s *Scanner) Output() []string {
+	return s.resultOutput
+}
+
+// Shuffle returns the seed the most recent package result ran its tests in the order of, or 0 without -shuffle
+func (s *Scanner) Shuffle() int64 {
+	return s.resultShuffle
+}
+
+// Err returns the first non-EOF error encountered while scanning
+func (s *Scanner) Err() error {
+	if s.err == io.EOF {
+		return nil
+	}
+	return s.err
+}
+
+// scanLine processes a single line of output, returning true if it completed a package result
+func (s *Scanner) scanLine(line []byte) bool {
+	if results := ParseLine(line); results != nil {
+		s.result = results
+		s.resultTests, s.resultOutput, s.resultShuffle = s.tests, s.output, s.shuffle
+		if build, ok := s.build[results[1]]; ok && strings.HasPrefix(results[2], "[") {
+			s.resultOutput = build
+		}
+		delete(s.build, results[1])
+
+		s.tests, s.output, s.current, s.lines, s.building, s.shuffle = nil, nil, -1, 0, "", 0
+		return true
+	}
+
+	text := string(line)
+	if match := buildRe.FindStringSubmatch(text); match != nil {
+		s.building = match[1]
+		s.build[s.building] = []string{text}
+		return false
+	}
+	if s.building != "" {
+		if !isMarker(text) {
+			if len(s.build[s.building]) < MaxOutputLines {
+				s.build[s.building] = append(s.build[s.building], text)
+			}
+			return false
+		}
+		s.building = ""
+	}
+
+	if match := shuffleRe.FindStringSubmatch(text); match != nil {
+		s.shuffle, _ = strconv.ParseInt(match[1], 10, 64)
+	}
+
+	// the retained output is capped, while test lines past it are still parsed
+	if s.lines < MaxOutputLines {
+		s.lines++
+		s.output = append(s.output, text)
+	}
+
+	if match := testRunRe.FindStringSubmatch(text); match != nil {
+		s.current = s.test(match[2])
+		return false
+	}
+	if match := testRe.FindStringSubmatch(text); match != nil {
+		s.current = s.test(match[2])
+		s.tests[s.current].Outcome = testOutcomes[match[1]]
+		s.tests[s.current].Duration, _ = strconv.ParseFloat(match[3], 64)
+		return false
+	}
+
+	// indented lines follow a test's outcome, while verbose output precedes it
+	if s.current >= 0 {
+		test := &s.tests[s.current]
+		retain := strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t") || (test.Outcome == "" && !isMarker(text))
+		if retain && len(test.Output) < MaxOutputLines {
+			test.Output = append(test.Output, text)
+		}
+	}
+
+	return false
+}
+
+// test returns the index of the named test, adding it if missing
+func (s *Scanner) test(name string) int {
+	for i := range s.tests {
+		if s.tests[i].Name == name {
+			return i
+		}
+	}
+
+	s.tests = append(s.tests, TestCase{Name: name})
+	return len(s.tests) - 1
+}
+
+// isMarker reports whether a line is printed by go test itself rather than a test
+func isMarker(text string) bool {
+	for _, prefix := range []string{"--- ", "=== ", "PASS", "FAIL", "ok ", "?   ", "exit status "} {
+		if strings.HasPrefix(text, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// readLine reads through the next newline, discarding anything beyond MaxLineLength
+func (s *Scanner) readLine() ([]byte, error) {
+	line, err := s.r.ReadSlice('\n')
+	line = append([]byte(nil), line...)
+	for err == bufio.ErrBufferFull {
+		_, err = s.r.ReadSlice('\n')
+	}
+
+	return line, err
+}
+
+// Open opens a test output file for reading, treating "-" as stdin
+func Open(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+
+	return os.Open(path)
+}
+
+// ParseReader reads test output from r to Parse() results
+func ParseReader(r io.Reader) ([][]string, error) {
+	packages := make([][]string, 0)
+	scanner := NewScanner(r)
+	for scanner.Scan() {
+		packages = append(packages, scanner.Result())
+	}
+
+	return packages, scanner.Err()
+}
+
+// ParseReaderFailed reads test output from r to ParseFailed() packages
+func ParseReaderFailed(r io.Reader) ([]string, error) {
+	packages := make([]string, 0)
+	scanner := NewScanner(r)
+	for scanner.Scan() {
+		if scanner.Result()[0] == "FAIL" {
+			packages = append(packages, scanner.Result()[1])
+		}
+	}
+
+	return packages, scanner.Err()
+}
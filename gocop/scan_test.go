@@ -0,0 +1,123 @@
+package gocop
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/poy/onpar"
+	"github.com/poy/onpar/expect"
+	"github.com/poy/onpar/matchers"
+)
+
+func TestScanner(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	tests := []struct {
+		name  string
+		input string
+		want  [][]string
+	}{
+		{
+			name:  "scans results incrementally",
+			input: "FAIL\tgithub.com/digitalocean/gocop/sample/fail\t0.600s\nok  \tgithub.com/digitalocean/gocop/sample/pass\t1.129s\n",
+			want: [][]string{{"FAIL", "github.com/digitalocean/gocop/sample/fail", "0.600s", ""},
+				{"ok", "github.com/digitalocean/gocop/sample/pass", "1.129s", ""},
+			},
+		},
+		{
+			name:  "skips lines longer than the maximum",
+			input: strings.Repeat("x", 3*MaxLineLength) + "\nok  \tgithub.com/digitalocean/gocop/sample/pass\t1.129s",
+			want: [][]string{
+				{"ok", "github.com/digitalocean/gocop/sample/pass", "1.129s", ""},
+			},
+		},
+		{
+			name:  "splits lines on carriage returns",
+			input: "ok  \tgithub.com/digitalocean/gocop/sample/pass\t1.129s\rFAIL\tgithub.com/digitalocean/gocop/sample/fail\t0.600s\r\n",
+			want: [][]string{{"ok", "github.com/digitalocean/gocop/sample/pass", "1.129s", ""},
+				{"FAIL", "github.com/digitalocean/gocop/sample/fail", "0.600s", ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		o.Spec(tt.name, func(expect expect.Expectation) {
+			got, err := ParseReader(strings.NewReader(tt.input))
+			expect(err).To(matchers.BeNil())
+			expect(got).To(matchers.Equal(tt.want))
+		})
+	}
+}
+
+func TestScannerTests(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	o.Spec("collects verbose test outcomes and output", func(expect expect.Expectation) {
+		input := `=== RUN   TestPass
+--- PASS: TestPass (0.01s)
+=== RUN   TestSkip
+    skip_test.go:9: needs a database
+--- SKIP: TestSkip (0.00s)
+=== RUN   TestFail
+=== RUN   TestFail/sub
+    fail_test.go:12: got 1
+--- FAIL: TestFail (0.02s)
+    --- FAIL: TestFail/sub (0.02s)
+FAIL
+FAIL	github.com/digitalocean/gocop/sample/fail	0.600s
+`
+		scanner := NewScanner(strings.NewReader(input))
+		expect(scanner.Scan()).To(matchers.BeTrue())
+		expect(scanner.Tests()).To(matchers.Equal([]TestCase{
+			{Name: "TestPass", Outcome: ResultPass, Duration: 0.01},
+			{Name: "TestSkip", Outcome: ResultSkip, Output: []string{"    skip_test.go:9: needs a database"}},
+			{Name: "TestFail", Outcome: ResultFail, Duration: 0.02},
+			{Name: "TestFail/sub", Outcome: ResultFail, Duration: 0.02, Output: []string{"    fail_test.go:12: got 1"}},
+		}))
+		expect(scanner.Scan()).To(matchers.BeFalse())
+	})
+
+	o.Spec("parses test outcomes past the retained output", func(expect expect.Expectation) {
+		var input strings.Builder
+		input.WriteString("=== RUN   TestLoud\n")
+		for i := 0; i < MaxOutputLines+500; i++ {
+			input.WriteString("    loud_test.go:10: line\n")
+		}
+		input.WriteString("--- PASS: TestLoud (0.01s)\n--- FAIL: TestLate (0.00s)\n    late_test.go:5: failed\nFAIL\n")
+		input.WriteString("FAIL\tgithub.com/digitalocean/gocop/sample/fail\t0.600s\n")
+
+		scanner := NewScanner(strings.NewReader(input.String()))
+		expect(scanner.Scan()).To(matchers.BeTrue())
+		expect(scanner.Output()).To(matchers.HaveLen(MaxOutputLines))
+		tests := scanner.Tests()
+		expect(tests).To(matchers.HaveLen(2))
+		expect(tests[0].Outcome).To(matchers.Equal(ResultPass))
+		expect(tests[0].Output).To(matchers.HaveLen(MaxOutputLines))
+		expect(tests[1]).To(matchers.Equal(TestCase{Name: "TestLate", Outcome: ResultFail, Output: []string{"    late_test.go:5: failed"}}))
+	})
+
+	o.Spec("records the -shuffle seed of each package", func(expect expect.Expectation) {
+		input := `-test.shuffle 1697040000123456789
+--- FAIL: TestEmpty (0.00s)
+    order_test.go:15: found 1 names registered by other tests
+FAIL
+FAIL	github.com/digitalocean/gocop/sample/order	0.004s
+ok  	github.com/digitalocean/gocop/sample/pass	0.002s
+`
+		scanner := NewScanner(strings.NewReader(input))
+		expect(scanner.Scan()).To(matchers.BeTrue())
+		expect(scanner.Shuffle()).To(matchers.Equal(int64(1697040000123456789)))
+		expect(scanner.Scan()).To(matchers.BeTrue())
+		expect(scanner.Shuffle()).To(matchers.Equal(int64(0)))
+	})
+}
@@ -0,0 +1,45 @@
+package gocop
+
+import (
+	"math"
+)
+
+// ZScore is the standard normal quantile for a two-sided confidence level, such as 1.96 for 0.95
+func ZScore(confidence float64) float64 {
+	return math.Sqrt2 * math.Erfinv(confidence)
+}
+
+// WilsonInterval estimates the bounds of a failure rate from failures observed in runs at a confidence level
+func WilsonInterval(failures, runs int, confidence float64) (float64, float64) {
+	if runs == 0 {
+		return 0, 1
+	}
+
+	z := ZScore(confidence)
+	n := float64(runs)
+	p := float64(failures) / n
+	denominator := 1 + z*z/n
+	center := (p + z*z/(2*n)) / denominator
+	margin := z * math.Sqrt(p*(1-p)/n+z*z/(4*n*n)) / denominator
+
+	low, high := math.Max(0, center-margin), math.Min(1, center+margin)
+	// the bounds are exact without failures or passes, which rounding would otherwise move
+	if failures == 0 {
+		low = 0
+	}
+	if failures == runs {
+		high = 1
+	}
+	return low, high
+}
+
+// RunsToDetect is how many runs observe at least one failure of a test failing at a rate, with a confidence
+func RunsToDetect(rate, confidence float64) int {
+	if rate >= 1 {
+		return 1
+	}
+	if rate <= 0 {
+		return 0
+	}
+	return int(math.Ceil(math.Log(1-confidence) / math.Log(1-rate)))
+}
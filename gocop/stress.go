@@ -0,0 +1,196 @@
+package gocop
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StressExamples limits how many output lines are kept as an example of each failure
+const StressExamples = 20
+
+var failRe = regexp.MustCompile(`^\s*--- FAIL: (\S+)`)
+
+// Stress runs a test binary repeatedly and in parallel to measure how often it fails
+type Stress struct {
+	Binary *TestBinary
+	// Args are passed to every run of the binary, such as -test.run
+	Args     []string
+	Parallel int
+	// Runs stops the stress after that many runs, unless zero
+	Runs int
+	// Duration stops starting new runs once elapsed, unless zero
+	Duration time.Duration
+	// Count is passed as -test.count, running the tests that many times in each run
+	Count   int
+	Timeout time.Duration
+	// Env is added to the environment of runs, such as GOMAXPROCS=2
+	Env        []string
+	Confidence float64
+}
+
+// StressFailure groups the failures of runs with the same fingerprint
+type StressFailure struct {
+	Fingerprint string   `json:"fingerprint"`
+	Runs        int      `json:"runs"`
+	Example     []string `json:"example"`
+}
+
+// StressReport is the failure rate of a package measured by a stress
+type StressReport struct {
+	Package  string          `json:"package"`
+	Runs     int             `json:"runs"`
+	Failures int             `json:"failures"`
+	Rate     float64         `json:"rate"`
+	Low      float64         `json:"low"`
+	High     float64         `json:"high"`
+	Elapsed  time.Duration   `json:"elapsed"`
+	Failed   []StressFailure `json:"failed"`
+	// Confidence is the confidence level of the Low and High bounds of the failure rate
+	Confidence float64 `json:"confidence"`
+}
+
+// Run runs the stress until it has run enough times or its duration elapsed
+func (s Stress) Run() (StressReport, error) {
+	report := StressReport{Package: s.Binary.Package, Failed: make([]StressFailure, 0), Confidence: s.Confidence}
+	args := append([]string{"-test.count=" + strconv.Itoa(s.Count)}, s.Args...)
+	parallel := s.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	var mu sync.Mutex
+	var firstErr error
+	started := 0
+	failures := make(map[string]*StressFailure)
+	start := time.Now()
+
+	// next claims a run, reporting false once the stress is done
+	next := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr != nil || (s.Runs > 0 && started >= s.Runs) || (s.Duration > 0 && time.Since(start) >= s.Duration) {
+			return false
+		}
+		started++
+		return true
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for next() {
+				passed, out, err := s.Binary.Run(args, s.Env, s.Timeout)
+
+				mu.Lock()
+				switch {
+				case err != nil:
+					if firstErr == nil {
+						firstErr = err
+					}
+				case passed:
+					report.Runs++
+				default:
+					report.Runs++
+					report.Failures++
+					lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+					fingerprint := FailureFingerprint(lines)
+					f, ok := failures[fingerprint]
+					if !ok {
+						if len(lines) > StressExamples {
+							lines = lines[:StressExamples]
+						}
+						f = &StressFailure{Fingerprint: fingerprint, Example: lines}
+						failures[fingerprint] = f
+					}
+					f.Runs++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	report.Elapsed = time.Since(start)
+	if report.Runs > 0 {
+		report.Rate = float64(report.Failures) / float64(report.Runs)
+	}
+	report.Low, report.High = WilsonInterval(report.Failures, report.Runs, s.Confidence)
+	for _, f := range failures {
+		report.Failed = append(report.Failed, *f)
+	}
+	sort.Slice(report.Failed, func(i, j int) bool {
+		if report.Failed[i].Runs != report.Failed[j].Runs {
+			return report.Failed[i].Runs > report.Failed[j].Runs
+		}
+		return report.Failed[i].Fingerprint < report.Failed[j].Fingerprint
+	})
+
+	return report, firstErr
+}
+
+// FailureFingerprint identifies the cause of a failed run from its output, so runs failing the same way
+// are grouped together
+//
+// It lists each failed test with the first source location it reported, falling back to the first line
+// of a panic or timeout.
+func FailureFingerprint(lines []string) string {
+	parts := make([]string, 0)
+	test := ""
+	located := false
+	for _, line := range lines {
+		if m := failRe.FindStringSubmatch(line); m != nil {
+			test = m[1]
+			located = false
+			parts = appendNew(parts, test)
+			continue
+		}
+
+		if test != "" && !located {
+			if m := sourceRe.FindStringSubmatch(line); m != nil {
+				located = true
+				parts[len(parts)-1] = test + " " + m[1] + ":" + m[2]
+			}
+		}
+	}
+	if len(parts) > 0 {
+		return strings.Join(parts, ", ")
+	}
+
+	for _, line := range lines {
+		if strings.HasPrefix(line, "panic: ") {
+			return line
+		}
+	}
+	for _, line := range lines {
+		if line != "" && line != "FAIL" && !strings.HasPrefix(line, "exit status") {
+			return line
+		}
+	}
+	return "failed without output"
+}
+
+// Text describes the failure rate and the failures by fingerprint
+func (r StressReport) Text() string {
+	text := fmt.Sprintf("%s: %d of %d runs failed in %s, failure rate %.2f%% (%.0f%% confidence interval %.2f%% to %.2f%%)",
+		r.Package, r.Failures, r.Runs, r.Elapsed.Round(time.Millisecond), r.Rate*100, r.Confidence*100, r.Low*100, r.High*100)
+	for _, f := range r.Failed {
+		text += fmt.Sprintf("\n%6d  %s", f.Runs, f.Fingerprint)
+	}
+	return text
+}
+
+// Records lists the failures by fingerprint with a header row
+func (r StressReport) Records() [][]string {
+	records := [][]string{{"package", "fingerprint", "runs", "example"}}
+	for _, f := range r.Failed {
+		records = append(records, []string{r.Package, f.Fingerprint, strconv.Itoa(f.Runs), strings.Join(f.Example, "\n")})
+	}
+	return records
+}
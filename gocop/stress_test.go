@@ -0,0 +1,83 @@
+package gocop
+
+import (
+	"testing"
+
+	"github.com/poy/onpar"
+	"github.com/poy/onpar/expect"
+	"github.com/poy/onpar/matchers"
+)
+
+func TestFailureFingerprint(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	o.Spec("identifies failed tests by their location", func(expect expect.Expectation) {
+		expect(FailureFingerprint([]string{
+			"--- FAIL: TestMightFail (0.00s)",
+			"    flaky_test.go:13: integer is factor of 3",
+			"    flaky_test.go:14: again",
+			"--- FAIL: TestOther (0.00s)",
+			"FAIL",
+		})).To(matchers.Equal("TestMightFail flaky_test.go:13, TestOther"))
+	})
+
+	o.Spec("falls back to panics and other output", func(expect expect.Expectation) {
+		expect(FailureFingerprint([]string{"panic: test timed out after 1s", "", "goroutine 1 [running]:"})).To(matchers.Equal("panic: test timed out after 1s"))
+		expect(FailureFingerprint([]string{"FAIL", "signal: killed"})).To(matchers.Equal("signal: killed"))
+		expect(FailureFingerprint(nil)).To(matchers.Equal("failed without output"))
+	})
+}
+
+func TestStress(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles test binaries")
+	}
+
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	stress := func(pkg string) (StressReport, error) {
+		binary, err := CompileTest(".", "github.com/digitalocean/gocop/sample/"+pkg, []string{"-tags", "sample"})
+		if err != nil {
+			return StressReport{}, err
+		}
+		defer binary.Remove()
+
+		return Stress{Binary: binary, Parallel: 4, Runs: 30, Count: 1, Confidence: 0.95}.Run()
+	}
+
+	o.Spec("measures the failure rate of flaky packages", func(expect expect.Expectation) {
+		report, err := stress("flaky")
+		expect(err).To(matchers.BeNil())
+		expect(report.Package).To(matchers.Equal("github.com/digitalocean/gocop/sample/flaky"))
+		expect(report.Runs).To(matchers.Equal(30))
+		expect(report.Failures > 0).To(matchers.BeTrue())
+		expect(report.Low <= report.Rate && report.Rate <= report.High).To(matchers.BeTrue())
+		expect(report.Failed).To(matchers.HaveLen(1))
+		expect(report.Failed[0].Fingerprint).To(matchers.Equal("TestMightFail flaky_test.go:13"))
+		expect(report.Failed[0].Runs).To(matchers.Equal(report.Failures))
+	})
+
+	o.Spec("reports no failures of passing packages", func(expect expect.Expectation) {
+		report, err := stress("pass")
+		expect(err).To(matchers.BeNil())
+		expect(report.Failures).To(matchers.Equal(0))
+		expect(report.Low).To(matchers.Equal(0.0))
+		expect(report.Records()).To(matchers.HaveLen(1))
+	})
+
+	o.Spec("rejects patterns matching several packages", func(expect expect.Expectation) {
+		_, err := CompileTest(".", "github.com/digitalocean/gocop/...", nil)
+		expect(err).To(matchers.Not(matchers.BeNil()))
+		expect(err.Error()).To(matchers.ContainSubstring("unable to resolve a single package"))
+	})
+}
@@ -0,0 +1,235 @@
+package gocop
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// SlowestPackages limits how many of the slowest packages are reported
+const SlowestPackages = 5
+
+// History contains stored results used to compare a run against earlier runs of its branch
+type History struct {
+	Previous []TestResult
+	Flaky    []string
+}
+
+// CoverageDelta reports the change in coverage of a package since the previous run
+type CoverageDelta struct {
+	Package  string  `json:"package"`
+	Coverage float64 `json:"coverage"`
+	Previous float64 `json:"previous"`
+	Delta    float64 `json:"delta"`
+}
+
+// RunReport summarizes a test run for review on a pull request
+type RunReport struct {
+	Summary      Summary         `json:"summary"`
+	Failed       PackageResults  `json:"failed"`
+	NewlyFailing []string        `json:"newly_failing"`
+	KnownFlaky   []string        `json:"known_flaky"`
+	Flaky        FlakyPackages   `json:"flaky"`
+	Coverage     []CoverageDelta `json:"coverage"`
+	Slowest      PackageResults  `json:"slowest"`
+	HasHistory   bool            `json:"has_history"`
+	// Owners groups failed and flaky packages by owner when owners were resolved
+	Owners []OwnerGroup `json:"owners,omitempty"`
+}
+
+// NewRunReport compares the results of a run, and any retests, against its history
+func NewRunReport(results PackageResults, flaky FlakyPackages, history *History) RunReport {
+	report := RunReport{
+		Summary:      Summarize(results),
+		Failed:       results.Outcome(ResultFail),
+		NewlyFailing: make([]string, 0),
+		KnownFlaky:   make([]string, 0),
+		Flaky:        flaky,
+		Coverage:     make([]CoverageDelta, 0),
+		HasHistory:   history != nil,
+	}
+	if report.Flaky == nil {
+		report.Flaky = make(FlakyPackages, 0)
+	}
+
+	slowest := make(PackageResults, 0)
+	for _, result := range results {
+		if result.Duration > 0 {
+			slowest = append(slowest, result)
+		}
+	}
+	sort.SliceStable(slowest, func(i, j int) bool { return slowest[i].Duration > slowest[j].Duration })
+	if len(slowest) > SlowestPackages {
+		slowest = slowest[:SlowestPackages]
+	}
+	report.Slowest = slowest
+
+	if report.Failed.owned() || report.Flaky.owned() {
+		names := make([]string, 0)
+		owners := make([][]string, 0)
+		for _, result := range report.Failed {
+			names = append(names, result.Package)
+			owners = append(owners, result.Owners)
+		}
+		for _, pkg := range report.Flaky {
+			names = append(names, pkg.Package)
+			owners = append(owners, pkg.Owners)
+		}
+		report.Owners = GroupByOwner(names, owners)
+	}
+
+	if history == nil {
+		return report
+	}
+
+	previous := make(map[string]TestResult)
+	for _, result := range history.Previous {
+		if result.Result != ResultFlaky {
+			previous[result.Package] = result
+		}
+	}
+	knownFlaky := make(map[string]bool)
+	for _, pkg := range history.Flaky {
+		knownFlaky[pkg] = true
+	}
+
+	for _, result := range report.Failed {
+		if knownFlaky[result.Package] {
+			report.KnownFlaky = append(report.KnownFlaky, result.Package)
+		} else if prev, ok := previous[result.Package]; !ok || prev.Result != ResultFail {
+			report.NewlyFailing = append(report.NewlyFailing, result.Package)
+		}
+	}
+
+	for _, result := range results {
+		prev, ok := previous[result.Package]
+		if !ok || result.Outcome != ResultPass || prev.Result != ResultPass {
+			continue
+		}
+
+		// stored coverage is a fraction rather than a percentage
+		delta := CoverageDelta{Package: result.Package, Coverage: result.Coverage, Previous: prev.Coverage * 100}
+		delta.Delta = math.Round((delta.Coverage-delta.Previous)*10) / 10
+		if delta.Delta != 0 {
+			report.Coverage = append(report.Coverage, delta)
+		}
+	}
+	sort.SliceStable(report.Coverage, func(i, j int) bool {
+		return math.Abs(report.Coverage[i].Delta) > math.Abs(report.Coverage[j].Delta)
+	})
+
+	return report
+}
+
+// Text describes the run totals in a single line
+func (r RunReport) Text() string {
+	return r.Summary.Text()
+}
+
+// Records lists failed packages with whether they are newly failing or known to be flaky
+func (r RunReport) Records() [][]string {
+	newly := make(map[string]bool)
+	for _, pkg := range r.NewlyFailing {
+		newly[pkg] = true
+	}
+	known := make(map[string]bool)
+	for _, pkg := range r.KnownFlaky {
+		known[pkg] = true
+	}
+
+	records := [][]string{{"package", "duration", "newly_failing", "known_flaky"}}
+	for _, result := range r.Failed {
+		records = append(records, []string{
+			result.Package,
+			strconv.FormatFloat(result.Duration, 'f', -1, 64),
+			strconv.FormatBool(newly[result.Package]),
+			strconv.FormatBool(known[result.Package]),
+		})
+	}
+
+	return records
+}
+
+// Annotations reports an error for each failure and a warning for each flaky test
+func (r RunReport) Annotations() []Annotation {
+	return append(r.Failed.Annotations(), r.Flaky.Annotations()...)
+}
+
+// Markdown renders the report for a pull request comment or job summary
+func (r RunReport) Markdown() (string, error) {
+	var buf bytes.Buffer
+	err := markdownTemplate.Execute(&buf, r)
+	return buf.String(), err
+}
+
+var markdownTemplate = template.Must(template.New("markdown").Funcs(template.FuncMap{
+	"fence":   func(lines []string) string { return strings.Replace(strings.Join(lines, "\n"), "```", "` ` `", -1) },
+	"seconds": func(f float64) string { return strconv.FormatFloat(f, 'f', 3, 64) + "s" },
+	"percent": func(f float64) string { return strconv.FormatFloat(f, 'f', 1, 64) + "%" },
+	"delta":   func(f float64) string { return strconv.FormatFloat(f, 'f', 1, 64) },
+	"failed":  failedTests,
+}).Parse(`## Test summary
+
+{{if .Summary.Failed}}:x:{{else}}:white_check_mark:{{end}} **{{.Summary.Packages}} packages**: {{.Summary.Passed}} passed, {{.Summary.Failed}} failed, {{.Summary.Skipped}} skipped in {{seconds .Summary.Duration}}
+{{if .NewlyFailing}}
+### Newly failing
+{{range .NewlyFailing}}
+- ` + "`{{.}}`" + `{{end}}
+{{end}}{{if .KnownFlaky}}
+### Known flakes
+{{range .KnownFlaky}}
+- ` + "`{{.}}`" + `{{end}}
+{{end}}{{if .Owners}}
+### Owners
+{{range .Owners}}
+- {{if .Owner}}{{.Owner}}{{else}}unowned{{end}}: {{range $i, $pkg := .Packages}}{{if $i}}, {{end}}` + "`{{$pkg}}`" + `{{end}}{{end}}
+{{end}}{{if .Flaky}}
+### Flaky in retests
+
+| Package | Failures | Attempts |
+| --- | ---: | ---: |
+{{range .Flaky}}| ` + "`{{.Package}}`" + ` | {{.Failures}} | {{.Attempts}} |
+{{end}}{{end}}{{if .Coverage}}
+### Coverage changes
+
+| Package | Coverage | Change |
+| --- | ---: | ---: |
+{{range .Coverage}}| ` + "`{{.Package}}`" + ` | {{percent .Coverage}} | {{if gt .Delta 0.0}}+{{end}}{{delta .Delta}} |
+{{end}}{{end}}{{if .Slowest}}
+### Slowest packages
+
+| Package | Duration |
+| --- | ---: |
+{{range .Slowest}}| ` + "`{{.Package}}`" + ` | {{seconds .Duration}} |
+{{end}}{{end}}{{if .Failed}}
+### Failures
+{{range .Failed}}{{$pkg := .}}{{$tests := failed .Tests}}{{if $tests}}{{range $tests}}
+<details><summary><code>{{$pkg.Package}}</code> {{.Name}}</summary>
+
+` + "```" + `
+{{fence .Output}}
+` + "```" + `
+
+</details>
+{{end}}{{else}}
+<details><summary><code>{{.Package}}</code> {{if .Status}}{{.Status}}{{else}}failed{{end}}</summary>
+
+` + "```" + `
+{{fence .Output}}
+` + "```" + `
+
+</details>
+{{end}}{{end}}{{end}}`))
+
+func failedTests(tests []TestCase) []TestCase {
+	failed := make([]TestCase, 0)
+	for _, test := range tests {
+		if test.Outcome == ResultFail {
+			failed = append(failed, test)
+		}
+	}
+	return failed
+}
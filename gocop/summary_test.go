@@ -0,0 +1,51 @@
+package gocop
+
+import (
+	"testing"
+
+	"github.com/poy/onpar"
+	"github.com/poy/onpar/expect"
+	"github.com/poy/onpar/matchers"
+)
+
+func TestNewRunReport(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	results := PackageResults{
+		{Package: "example.com/fail", Outcome: ResultFail, Duration: 1},
+		{Package: "example.com/broken", Outcome: ResultFail, Duration: 2},
+		{Package: "example.com/flaky", Outcome: ResultFail, Duration: 3},
+		{Package: "example.com/pass", Outcome: ResultPass, Duration: 4, Coverage: 52.5},
+	}
+	history := &History{
+		Previous: []TestResult{
+			{Package: "example.com/fail", Result: ResultPass},
+			{Package: "example.com/broken", Result: ResultFail},
+			{Package: "example.com/flaky", Result: ResultPass},
+			{Package: "example.com/pass", Result: ResultPass, Coverage: 0.5},
+		},
+		Flaky: []string{"example.com/flaky"},
+	}
+
+	o.Spec("compares a run against its history", func(expect expect.Expectation) {
+		report := NewRunReport(results, nil, history)
+		expect(report.NewlyFailing).To(matchers.Equal([]string{"example.com/fail"}))
+		expect(report.KnownFlaky).To(matchers.Equal([]string{"example.com/flaky"}))
+		expect(report.Coverage).To(matchers.Equal([]CoverageDelta{
+			{Package: "example.com/pass", Coverage: 52.5, Previous: 50, Delta: 2.5},
+		}))
+		expect(report.Slowest[0].Package).To(matchers.Equal("example.com/pass"))
+	})
+
+	o.Spec("omits comparisons without history", func(expect expect.Expectation) {
+		report := NewRunReport(results, nil, nil)
+		expect(report.HasHistory).To(matchers.BeFalse())
+		expect(report.NewlyFailing).To(matchers.HaveLen(0))
+		expect(len(report.Failed)).To(matchers.Equal(3))
+	})
+}
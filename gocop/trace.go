@@ -0,0 +1,224 @@
+package gocop
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Span is a timed operation in the trace of a test run
+type Span struct {
+	Name       string
+	Start      time.Time
+	End        time.Time
+	Attributes []Attribute
+	Failed     bool
+	Message    string
+	Children   []*Span
+}
+
+// Attribute is a key and a string, bool, int64, float64 or []string value describing a span
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// timing is the start and end of a package or test taken from test2json events
+type timing struct {
+	start time.Time
+	end   time.Time
+}
+
+// ReadTrace reads test output in any supported input format into a trace of a run
+//
+// The output is streamed, keeping only package results and the timings of go test -json events.
+func ReadTrace(r io.Reader, run TestRun) (*Span, error) {
+	results := make(PackageResults, 0)
+	collect := func(result PackageResult) error {
+		results = append(results, result)
+		return nil
+	}
+
+	br := bufio.NewReaderSize(r, MaxLineLength)
+	if DetectInput(br) != InputJSON {
+		err := EachResult(br, collect)
+		if err != nil {
+			return nil, err
+		}
+		return buildTrace(run, results, nil), nil
+	}
+
+	timings := make(map[string]timing)
+	err := eachEventResult(br, func(event TestEvent) { addTiming(timings, event) }, collect)
+	if err != nil {
+		return nil, err
+	}
+	return buildTrace(run, results, timings), nil
+}
+
+// NewTrace builds a trace with a span for the run, its packages and their tests
+//
+// Spans are timed by test2json events when available, otherwise packages are assumed to start with
+// the run and their tests to run one after another.
+func NewTrace(run TestRun, results PackageResults, events []TestEvent) *Span {
+	var timings map[string]timing
+	if len(events) > 0 {
+		timings = make(map[string]timing)
+		for _, event := range events {
+			addTiming(timings, event)
+		}
+	}
+	return buildTrace(run, results, timings)
+}
+
+// buildTrace builds the trace of a run from the timings of its events, estimating them when nil
+func buildTrace(run TestRun, results PackageResults, timings map[string]timing) *Span {
+	root := &Span{
+		Name:       "go test",
+		Start:      run.Created,
+		End:        run.Created,
+		Attributes: runAttributes(run),
+	}
+	if timings == nil {
+		root.Attributes = append(root.Attributes, Attribute{Key: "gocop.timing.estimated", Value: true})
+	}
+
+	failed := 0
+	for i, result := range results {
+		span := packageSpan(run.Created, result, timings)
+		if i == 0 || span.Start.Before(root.Start) {
+			root.Start = span.Start
+		}
+		if i == 0 || span.End.After(root.End) {
+			root.End = span.End
+		}
+		if span.Failed {
+			failed++
+		}
+		root.Children = append(root.Children, span)
+	}
+
+	if failed > 0 {
+		root.Failed = true
+		root.Message = fmt.Sprintf("%d packages failed", failed)
+	}
+
+	return root
+}
+
+func packageSpan(created time.Time, result PackageResult, timings map[string]timing) *Span {
+	span := &Span{
+		Name: result.Package,
+		Attributes: []Attribute{
+			{Key: "gocop.package", Value: result.Package},
+			{Key: "gocop.result", Value: result.Outcome},
+		},
+	}
+	if result.Status != "" {
+		span.Attributes = append(span.Attributes, Attribute{Key: "gocop.status", Value: result.Status})
+	}
+	if result.Coverage > 0 {
+		span.Attributes = append(span.Attributes, Attribute{Key: "gocop.coverage", Value: result.Coverage})
+	}
+
+	if t, ok := timings[result.Package]; ok {
+		span.Start, span.End = t.start, t.end
+	} else {
+		span.Start = created
+		span.End = created.Add(seconds(result.Duration))
+	}
+
+	if result.Outcome == ResultFail {
+		span.Failed = true
+		span.Message = junitMessage(result.Output, statusOr(result.Status, "failed")).Message
+	}
+
+	// subtests are children of their parent test, which is always reported before them
+	tests := make(map[string]*Span)
+	next := span.Start
+	for _, test := range result.Tests {
+		child := &Span{
+			Name: test.Name,
+			Attributes: []Attribute{
+				{Key: "gocop.package", Value: result.Package},
+				{Key: "gocop.test", Value: test.Name},
+				{Key: "gocop.result", Value: test.Outcome},
+			},
+		}
+
+		if t, ok := timings[result.Package+"\x00"+test.Name]; ok {
+			child.Start, child.End = t.start, t.end
+		} else {
+			child.Start = next
+			child.End = next.Add(seconds(test.Duration))
+			if !strings.Contains(test.Name, "/") {
+				next = child.End
+			}
+		}
+
+		if test.Outcome == ResultFail {
+			child.Failed = true
+			child.Message = junitMessage(test.Output, "failed").Message
+		}
+
+		parent := span
+		if i := strings.LastIndex(test.Name, "/"); i >= 0 {
+			if p, ok := tests[test.Name[:i]]; ok {
+				parent = p
+			}
+		}
+		parent.Children = append(parent.Children, child)
+		tests[test.Name] = child
+	}
+
+	return span
+}
+
+// addTiming extends the start and end of the package of an event, and of its test keyed by package and name
+func addTiming(timings map[string]timing, event TestEvent) {
+	if event.Package == "" || event.Time.IsZero() {
+		return
+	}
+
+	key := event.Package
+	if event.Test != "" {
+		key += "\x00" + event.Test
+	}
+
+	t, ok := timings[key]
+	if !ok {
+		t.start = event.Time
+	}
+	switch event.Action {
+	case "pass", "fail", "skip":
+		t.end = event.Time
+	}
+	if t.end.Before(t.start) {
+		t.end = t.start
+	}
+	timings[key] = t
+}
+
+func runAttributes(run TestRun) []Attribute {
+	attributes := []Attribute{
+		{Key: "gocop.run.repo", Value: run.Repo},
+		{Key: "gocop.run.branch", Value: run.Branch},
+		{Key: "gocop.run.sha", Value: run.Sha},
+		{Key: "gocop.run.build_id", Value: run.BuildID},
+		{Key: "gocop.run.cmd", Value: run.Command},
+		{Key: "gocop.run.benchmark", Value: run.Benchmark},
+		{Key: "gocop.run.short", Value: run.Short},
+		{Key: "gocop.run.race", Value: run.Race},
+	}
+	if len(run.Tags) > 0 {
+		attributes = append(attributes, Attribute{Key: "gocop.run.tags", Value: run.Tags})
+	}
+
+	return attributes
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
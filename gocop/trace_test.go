@@ -0,0 +1,98 @@
+package gocop
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/poy/onpar"
+	"github.com/poy/onpar/expect"
+	"github.com/poy/onpar/matchers"
+)
+
+func TestTrace(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	run := TestRun{Repo: "gocop", Created: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+	o.Spec("times spans by test2json events", func(expect expect.Expectation) {
+		f, err := os.Open("testdata/run0.json")
+		expect(err).To(matchers.BeNil())
+		defer f.Close()
+
+		root, err := ReadTrace(f, run)
+		expect(err).To(matchers.BeNil())
+		expect(root.Failed).To(matchers.BeTrue())
+		expect(root.Children).To(matchers.HaveLen(5))
+
+		pkg := root.Children[0]
+		expect(pkg.Name).To(matchers.Equal("github.com/digitalocean/gocop/sample/fail"))
+		expect(pkg.Start.Equal(time.Date(2026, 10, 19, 10, 18, 34, 785359999, time.UTC))).To(matchers.BeTrue())
+		expect(pkg.Children[0].Message).To(matchers.Equal("failing_test.go:13: number does equal eleven"))
+		expect(root.Start.Equal(pkg.Start)).To(matchers.BeTrue())
+		expect(root.Attributes).To(matchers.Not(matchers.Contain(Attribute{Key: "gocop.timing.estimated", Value: true})))
+	})
+
+	o.Spec("estimates timing of text output", func(expect expect.Expectation) {
+		f, err := os.Open("testdata/run1.txt")
+		expect(err).To(matchers.BeNil())
+		defer f.Close()
+
+		root, err := ReadTrace(f, run)
+		expect(err).To(matchers.BeNil())
+		expect(root.Failed).To(matchers.BeTrue())
+		expect(root.Attributes).To(matchers.Contain(Attribute{Key: "gocop.timing.estimated", Value: true}))
+	})
+
+	o.Spec("estimates timing and nests subtests without events", func(expect expect.Expectation) {
+		results := PackageResults{{
+			Package:  "example.com/pkg",
+			Outcome:  ResultPass,
+			Duration: 3,
+			Tests: []TestCase{
+				{Name: "TestA", Outcome: ResultPass, Duration: 1},
+				{Name: "TestA/sub", Outcome: ResultPass, Duration: 1},
+				{Name: "TestB", Outcome: ResultPass, Duration: 2},
+			},
+		}}
+
+		root := NewTrace(run, results, nil)
+		expect(root.End.Sub(root.Start)).To(matchers.Equal(3 * time.Second))
+
+		pkg := root.Children[0]
+		expect(pkg.Children).To(matchers.HaveLen(2))
+		expect(pkg.Children[0].Children[0].Name).To(matchers.Equal("TestA/sub"))
+		expect(pkg.Children[1].Start.Sub(run.Created)).To(matchers.Equal(time.Second))
+	})
+
+	o.Spec("exports spans linked to their parents", func(expect expect.Expectation) {
+		var body []byte
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			expect(r.Header.Get("Authorization")).To(matchers.Equal("Bearer secret"))
+			body, _ = ioutil.ReadAll(r.Body)
+		}))
+		defer srv.Close()
+
+		root := NewTrace(run, PackageResults{{Package: "example.com/pkg", Outcome: ResultFail, Output: []string{"boom"}}}, nil)
+		err := ExportOTLP(srv.URL, map[string]string{"Authorization": "Bearer secret"}, "gocop", root)
+		expect(err).To(matchers.BeNil())
+
+		var req otlpRequest
+		expect(json.NewDecoder(bytes.NewReader(body)).Decode(&req)).To(matchers.BeNil())
+		spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+		expect(spans).To(matchers.HaveLen(2))
+		expect(spans[1].ParentSpanID).To(matchers.Equal(spans[0].SpanID))
+		expect(spans[1].TraceID).To(matchers.Equal(spans[0].TraceID))
+		expect(spans[1].Status).To(matchers.Equal(&otlpStatus{Code: otlpStatusError, Message: "boom"}))
+	})
+}
@@ -0,0 +1,218 @@
+package gocop
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VerifyConfig is a configuration the tests of a package are run under when verifying a fix
+type VerifyConfig struct {
+	// GOMAXPROCS is set in the environment of runs, unless zero
+	GOMAXPROCS int `json:"gomaxprocs,omitempty"`
+	// CPU is passed as -test.cpu, a comma-separated list of GOMAXPROCS values tests run with in turn
+	CPU     string `json:"cpu,omitempty"`
+	Shuffle bool   `json:"shuffle,omitempty"`
+}
+
+// Name describes the configuration in the form of the environment and flags it sets
+func (c VerifyConfig) Name() string {
+	parts := make([]string, 0)
+	if c.GOMAXPROCS > 0 {
+		parts = append(parts, "GOMAXPROCS="+strconv.Itoa(c.GOMAXPROCS))
+	}
+	if c.CPU != "" {
+		parts = append(parts, "-cpu="+c.CPU)
+	}
+	if c.Shuffle {
+		parts = append(parts, "-shuffle=on")
+	}
+	if len(parts) == 0 {
+		return "default"
+	}
+	return strings.Join(parts, " ")
+}
+
+// Verify runs a package, or a single test, repeatedly under several configurations to check that it is no
+// longer flaky
+//
+// The fix is verified when the upper bound of the confidence interval of the failure rate across all runs is
+// at most MaxFailureRate.
+type Verify struct {
+	Binary *TestBinary
+	// Test limits the runs to a test, or a subtest given as Test/Subtest
+	Test string
+	// Args are passed to every run of the binary
+	Args []string
+	// Runs are split evenly between the configurations
+	Runs           int
+	Parallel       int
+	Configs        []VerifyConfig
+	Timeout        time.Duration
+	MaxFailureRate float64
+	Confidence     float64
+}
+
+// VerifyResult is the outcome of the runs under a configuration
+type VerifyResult struct {
+	Config   string          `json:"config"`
+	Runs     int             `json:"runs"`
+	Failures int             `json:"failures"`
+	Failed   []StressFailure `json:"failed"`
+}
+
+// VerifyReport tells whether a fix of a flaky package or test was verified
+type VerifyReport struct {
+	Package        string         `json:"package"`
+	Test           string         `json:"test,omitempty"`
+	Runs           int            `json:"runs"`
+	Failures       int            `json:"failures"`
+	Low            float64        `json:"low"`
+	High           float64        `json:"high"`
+	MaxFailureRate float64        `json:"max_failure_rate"`
+	Confidence     float64        `json:"confidence"`
+	Verified       bool           `json:"verified"`
+	Elapsed        time.Duration  `json:"elapsed"`
+	Configs        []VerifyResult `json:"configs"`
+}
+
+// Run runs the tests under each configuration in turn
+func (v Verify) Run() (VerifyReport, error) {
+	report := VerifyReport{
+		Package:        v.Binary.Package,
+		Test:           v.Test,
+		MaxFailureRate: v.MaxFailureRate,
+		Confidence:     v.Confidence,
+		Configs:        make([]VerifyResult, 0),
+	}
+
+	configs := v.Configs
+	if len(configs) == 0 {
+		configs = []VerifyConfig{{}}
+	}
+
+	start := time.Now()
+	for i, config := range configs {
+		runs := v.Runs / len(configs)
+		if i < v.Runs%len(configs) {
+			runs++
+		}
+		if runs == 0 {
+			continue
+		}
+
+		stress, err := Stress{
+			Binary:     v.Binary,
+			Args:       append(append([]string{}, v.Args...), config.args(v.Test)...),
+			Parallel:   v.Parallel,
+			Runs:       runs,
+			Count:      1,
+			Timeout:    v.Timeout,
+			Env:        config.env(),
+			Confidence: v.Confidence,
+		}.Run()
+		if err != nil {
+			return report, err
+		}
+
+		report.Runs += stress.Runs
+		report.Failures += stress.Failures
+		report.Configs = append(report.Configs, VerifyResult{
+			Config:   config.Name(),
+			Runs:     stress.Runs,
+			Failures: stress.Failures,
+			Failed:   stress.Failed,
+		})
+	}
+
+	report.Elapsed = time.Since(start)
+	report.Low, report.High = WilsonInterval(report.Failures, report.Runs, v.Confidence)
+	report.Verified = report.Runs > 0 && report.High <= v.MaxFailureRate
+
+	return report, nil
+}
+
+func (c VerifyConfig) args(test string) []string {
+	args := make([]string, 0)
+	if test != "" {
+		args = append(args, "-test.run", RunPattern(test))
+	}
+	if c.CPU != "" {
+		args = append(args, "-test.cpu", c.CPU)
+	}
+	if c.Shuffle {
+		args = append(args, "-test.shuffle", "on")
+	}
+	return args
+}
+
+func (c VerifyConfig) env() []string {
+	if c.GOMAXPROCS > 0 {
+		return []string{"GOMAXPROCS=" + strconv.Itoa(c.GOMAXPROCS)}
+	}
+	return nil
+}
+
+// RunPattern is the -run pattern matching exactly a test, or a subtest given as Test/Subtest
+func RunPattern(test string) string {
+	parts := strings.Split(test, "/")
+	for i, part := range parts {
+		parts[i] = "^" + regexp.QuoteMeta(part) + "$"
+	}
+	return strings.Join(parts, "/")
+}
+
+// Results converts the runs for storage as a run, the package being flaky when it both passed and failed
+func (r VerifyReport) Results() (PackageResults, FlakyPackages) {
+	outcome := ResultPass
+	if r.Failures > 0 {
+		outcome = ResultFail
+	}
+
+	result := PackageResult{Package: r.Package, Outcome: outcome}
+	var flaky FlakyPackages
+	if r.Failures > 0 && r.Failures < r.Runs {
+		pkg := FlakyPackage{Package: r.Package, Attempts: r.Runs, Failures: r.Failures}
+		if r.Test != "" {
+			pkg.Tests = []FlakyTest{{Name: r.Test, Attempts: r.Runs, Failures: r.Failures}}
+		}
+		flaky = FlakyPackages{pkg}
+	} else if r.Test != "" {
+		result.Tests = []TestCase{{Name: r.Test, Outcome: outcome}}
+	}
+
+	return PackageResults{result}, flaky
+}
+
+// Text describes whether the fix was verified, with the failures under each configuration
+func (r VerifyReport) Text() string {
+	subject := r.Package
+	if r.Test != "" {
+		subject = r.Test + " in " + r.Package
+	}
+	verdict := "verified"
+	if !r.Verified {
+		verdict = "not verified"
+	}
+
+	text := fmt.Sprintf("%s %s: %d of %d runs failed in %s, failure rate at most %.2f%% with %.0f%% confidence, accepting %.2f%%",
+		subject, verdict, r.Failures, r.Runs, r.Elapsed.Round(time.Millisecond), r.High*100, r.Confidence*100, r.MaxFailureRate*100)
+	for _, c := range r.Configs {
+		text += fmt.Sprintf("\n  %s: %d of %d runs failed", c.Config, c.Failures, c.Runs)
+		for _, f := range c.Failed {
+			text += fmt.Sprintf("\n  %6d  %s", f.Runs, f.Fingerprint)
+		}
+	}
+	return text
+}
+
+// Records lists the runs and failures of each configuration with a header row
+func (r VerifyReport) Records() [][]string {
+	records := [][]string{{"package", "test", "config", "runs", "failures", "verified"}}
+	for _, c := range r.Configs {
+		records = append(records, []string{r.Package, r.Test, c.Config, strconv.Itoa(c.Runs), strconv.Itoa(c.Failures), strconv.FormatBool(r.Verified)})
+	}
+	return records
+}
@@ -0,0 +1,92 @@
+package gocop
+
+import (
+	"testing"
+
+	"github.com/poy/onpar"
+	"github.com/poy/onpar/expect"
+	"github.com/poy/onpar/matchers"
+)
+
+func TestVerifyReport(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	o.Spec("matches exactly a test or subtest", func(expect expect.Expectation) {
+		expect(RunPattern("TestMightFail")).To(matchers.Equal("^TestMightFail$"))
+		expect(RunPattern("TestTable/a.b")).To(matchers.Equal(`^TestTable$/^a\.b$`))
+	})
+
+	o.Spec("names configurations by what they set", func(expect expect.Expectation) {
+		expect(VerifyConfig{}.Name()).To(matchers.Equal("default"))
+		expect(VerifyConfig{GOMAXPROCS: 2, CPU: "1,4", Shuffle: true}.Name()).To(matchers.Equal("GOMAXPROCS=2 -cpu=1,4 -shuffle=on"))
+	})
+
+	o.Spec("stores runs which both passed and failed as flaky", func(expect expect.Expectation) {
+		results, flaky := VerifyReport{Package: "pkg", Test: "TestA", Runs: 10, Failures: 2}.Results()
+		expect(results).To(matchers.HaveLen(1))
+		expect(results[0].Outcome).To(matchers.Equal(ResultFail))
+		expect(flaky).To(matchers.HaveLen(1))
+		expect(flaky[0].Tests).To(matchers.HaveLen(1))
+		expect(flaky[0].Tests[0].Failures).To(matchers.Equal(2))
+	})
+
+	o.Spec("stores passing runs as passed", func(expect expect.Expectation) {
+		results, flaky := VerifyReport{Package: "pkg", Test: "TestA", Runs: 10}.Results()
+		expect(results[0].Outcome).To(matchers.Equal(ResultPass))
+		expect(results[0].Tests).To(matchers.HaveLen(1))
+		expect(flaky).To(matchers.HaveLen(0))
+	})
+}
+
+func TestVerify(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles test binaries")
+	}
+
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	verify := func(pkg, test string) (VerifyReport, error) {
+		binary, err := CompileTest(".", "github.com/digitalocean/gocop/sample/"+pkg, []string{"-tags", "sample"})
+		if err != nil {
+			return VerifyReport{}, err
+		}
+		defer binary.Remove()
+
+		return Verify{
+			Binary:         binary,
+			Test:           test,
+			Runs:           60,
+			Parallel:       4,
+			Configs:        []VerifyConfig{{GOMAXPROCS: 1}, {GOMAXPROCS: 2, Shuffle: true}},
+			MaxFailureRate: 0.1,
+			Confidence:     0.95,
+		}.Run()
+	}
+
+	o.Spec("verifies passing packages", func(expect expect.Expectation) {
+		report, err := verify("pass", "")
+		expect(err).To(matchers.BeNil())
+		expect(report.Runs).To(matchers.Equal(60))
+		expect(report.Configs).To(matchers.HaveLen(2))
+		expect(report.Configs[0].Runs).To(matchers.Equal(30))
+		expect(report.Verified).To(matchers.BeTrue())
+	})
+
+	o.Spec("does not verify flaky tests", func(expect expect.Expectation) {
+		report, err := verify("flaky", "TestMightFail")
+		expect(err).To(matchers.BeNil())
+		expect(report.Failures > 0).To(matchers.BeTrue())
+		expect(report.Verified).To(matchers.Equal(false))
+		expect(report.Text()).To(matchers.ContainSubstring("TestMightFail in github.com/digitalocean/gocop/sample/flaky not verified"))
+	})
+}
@@ -0,0 +1,27 @@
+// +build sample
+
+package order
+
+import (
+	"testing"
+)
+
+var registered []string
+
+func TestAlpha(t *testing.T) {}
+
+func TestEmpty(t *testing.T) {
+	if len(registered) != 0 {
+		t.Errorf("found %d names registered by other tests", len(registered))
+	}
+}
+
+func TestBeta(t *testing.T) {}
+
+func TestGamma(t *testing.T) {}
+
+func TestRegister(t *testing.T) {
+	registered = append(registered, "gopher")
+}
+
+func TestDelta(t *testing.T) {}
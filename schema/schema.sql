@@ -1,4 +1,8 @@
 -- DOWN
+DROP TABLE IF EXISTS issue;
+DROP TABLE IF EXISTS announcement;
+DROP TABLE IF EXISTS quarantine;
+DROP TABLE IF EXISTS testcase;
 DROP TABLE IF EXISTS test;
 DROP TABLE IF EXISTS run CASCADE;
 
@@ -17,7 +21,10 @@ CREATE TABLE run (
   short     BOOL,
   tags      TEXT,
   hash      TEXT,
-  duration  INTEGER
+  duration  INTEGER,
+  kind      TEXT NOT NULL DEFAULT 'ci' CHECK (kind in ('ci', 'verify')),
+  metadata  JSONB,
+  flags     JSONB
 );
 
 SELECT create_hypertable('run', 'created');
@@ -52,7 +59,58 @@ CREATE TABLE test (
   package   TEXT,
   result    TEXT CHECK (result in ('pass', 'fail', 'flaky', 'skip')),
   duration  INTEGER,
-  coverage  NUMERIC(4,3)
+  coverage  NUMERIC(4,3),
+  shuffle   BIGINT
 );
 
 SELECT create_hypertable('test', 'created');
+
+DROP TABLE IF EXISTS testcase;
+CREATE TABLE testcase (
+  created   TIMESTAMPTZ,
+  package   TEXT,
+  name      TEXT,
+  result    TEXT CHECK (result in ('pass', 'fail', 'flaky', 'skip')),
+  duration  INTEGER,
+  output    TEXT
+);
+
+SELECT create_hypertable('testcase', 'created');
+
+DROP TABLE IF EXISTS quarantine;
+CREATE TABLE quarantine (
+  repo      TEXT NOT NULL,
+  package   TEXT NOT NULL,
+  test      TEXT NOT NULL DEFAULT '',
+  reason    TEXT,
+  issue     TEXT,
+  created   TIMESTAMPTZ NOT NULL DEFAULT now(),
+  resolved  TIMESTAMPTZ,
+  PRIMARY KEY (repo, package, test)
+);
+
+DROP TABLE IF EXISTS announcement;
+CREATE TABLE announcement (
+  repo      TEXT NOT NULL,
+  branch    TEXT NOT NULL,
+  package   TEXT NOT NULL,
+  kind      TEXT CHECK (kind in ('failing', 'flaky')),
+  created   TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (repo, branch, package, kind)
+);
+
+DROP TABLE IF EXISTS issue;
+CREATE TABLE issue (
+  repo        TEXT NOT NULL,
+  package     TEXT NOT NULL,
+  test        TEXT NOT NULL DEFAULT '',
+  tracker     TEXT NOT NULL,
+  key         TEXT NOT NULL,
+  url         TEXT,
+  state       TEXT CHECK (state in ('open', 'closed')),
+  occurrences INTEGER,
+  last_seen   TIMESTAMPTZ,
+  created     TIMESTAMPTZ NOT NULL,
+  updated     TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (repo, package, test, tracker)
+);
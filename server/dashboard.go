@@ -0,0 +1,405 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// started is reported as the modification time of the dashboard assets, which are fixed for the life of the binary
+var started = time.Now()
+
+// assets are the static files of the dashboard, compiled into the binary
+var assets = map[string]struct {
+	contentType string
+	content     string
+}{
+	"app.js":    {"application/javascript; charset=utf-8", dashboardJS},
+	"style.css": {"text/css; charset=utf-8", dashboardCSS},
+}
+
+func serveIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	http.ServeContent(w, r, "index.html", started, strings.NewReader(dashboardHTML))
+}
+
+func serveStatic(w http.ResponseWriter, r *http.Request) {
+	asset, ok := assets[strings.TrimPrefix(r.URL.Path, "/static/")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", asset.contentType)
+	http.ServeContent(w, r, r.URL.Path, started, strings.NewReader(asset.content))
+}
+
+const dashboardHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>gocop</title>
+<link rel="stylesheet" href="/static/style.css">
+</head>
+<body>
+<header>
+<a class="brand" href="#/runs">gocop</a>
+<nav>
+<a href="#/runs">Runs</a>
+<a href="#/flaky">Flaky</a>
+<a href="#/quarantine">Quarantine</a>
+</nav>
+</header>
+<main id="main"></main>
+<script src="/static/app.js"></script>
+</body>
+</html>
+`
+
+const dashboardCSS = `body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 0; color: #24292e; }
+header { display: flex; align-items: center; gap: 1.5em; padding: 0.8em 2em; background: #24292e; }
+header a { color: #fff; text-decoration: none; }
+header .brand { font-weight: bold; font-size: 1.2em; }
+header nav a { margin-right: 1em; opacity: 0.8; }
+header nav a:hover { opacity: 1; }
+main { padding: 1em 2em; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 1.5em; }
+th, td { text-align: left; padding: 0.4em 0.6em; border-bottom: 1px solid #e1e4e8; }
+th { background: #f6f8fa; }
+form { margin: 1em 0; display: flex; gap: 0.5em; flex-wrap: wrap; align-items: center; }
+input { padding: 0.3em 0.5em; border: 1px solid #d1d5da; border-radius: 3px; }
+button { padding: 0.3em 0.8em; border: 1px solid #d1d5da; border-radius: 3px; background: #fafbfc; cursor: pointer; }
+.pass { color: #22863a; }
+.fail { color: #cb2431; }
+.flaky { color: #b08800; }
+.skip { color: #6a737d; }
+.error { color: #cb2431; }
+.timeline { display: flex; flex-wrap: wrap; gap: 2px; margin-bottom: 1.5em; }
+.timeline span { width: 12px; height: 24px; display: inline-block; border-radius: 2px; }
+.timeline .pass { background: #2cbe4e; }
+.timeline .fail { background: #cb2431; }
+.timeline .flaky { background: #f9c513; }
+.timeline .skip { background: #d1d5da; }
+svg.chart { width: 100%; max-width: 800px; height: 180px; margin-bottom: 1.5em; }
+svg.chart polyline { fill: none; stroke: #2188ff; stroke-width: 2; }
+svg.chart text { font-size: 11px; fill: #6a737d; }
+svg.chart line { stroke: #e1e4e8; }
+.pager { display: flex; gap: 1em; }
+`
+
+const dashboardJS = `(function () {
+  'use strict';
+
+  var main = document.getElementById('main');
+  var svgNS = 'http://www.w3.org/2000/svg';
+
+  function el(tag, attrs, children) {
+    var node = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function (key) {
+      if (key.indexOf('on') === 0) {
+        node.addEventListener(key.substring(2), attrs[key]);
+      } else {
+        node.setAttribute(key, attrs[key]);
+      }
+    });
+    (children || []).forEach(function (child) {
+      if (child === null || child === undefined) {
+        return;
+      }
+      node.appendChild(typeof child === 'object' ? child : document.createTextNode(String(child)));
+    });
+    return node;
+  }
+
+  function svg(tag, attrs, children) {
+    var node = document.createElementNS(svgNS, tag);
+    Object.keys(attrs || {}).forEach(function (key) {
+      node.setAttribute(key, attrs[key]);
+    });
+    (children || []).forEach(function (child) {
+      node.appendChild(typeof child === 'object' ? child : document.createTextNode(String(child)));
+    });
+    return node;
+  }
+
+  function query(params) {
+    var parts = Object.keys(params).filter(function (key) {
+      return params[key] !== undefined && params[key] !== null && params[key] !== '';
+    }).map(function (key) {
+      return encodeURIComponent(key) + '=' + encodeURIComponent(params[key]);
+    });
+    return parts.length ? '?' + parts.join('&') : '';
+  }
+
+  // changes such as quarantining require one of the server's tokens, asked for once and kept for the session
+  var tokenKey = 'gocop-token';
+
+  function api(path, params, options, retried) {
+    options = options || {};
+    var token = sessionStorage.getItem(tokenKey);
+    if (options.method && token) {
+      options.headers = { 'Authorization': 'Bearer ' + token };
+    }
+    return fetch('/api/' + path + query(params || {}), options).then(function (res) {
+      if (res.status === 401 && options.method && !retried) {
+        var entered = window.prompt('Token to change the quarantine');
+        if (entered) {
+          sessionStorage.setItem(tokenKey, entered);
+          return api(path, params, options, true);
+        }
+      }
+      if (res.status === 204) {
+        return null;
+      }
+      return res.json().then(function (body) {
+        if (!res.ok) {
+          throw new Error(body.error || res.statusText);
+        }
+        return body;
+      });
+    });
+  }
+
+  function link(page, params, text) {
+    return el('a', { href: '#/' + page + query(params) }, [text]);
+  }
+
+  function seconds(s) {
+    return s.toFixed(3) + 's';
+  }
+
+  function percent(p) {
+    return p.toFixed(1) + '%';
+  }
+
+  function time(t) {
+    return new Date(t).toLocaleString();
+  }
+
+  function outcome(result) {
+    return el('span', { 'class': result }, [result]);
+  }
+
+  function table(headers, rows) {
+    return el('table', {}, [
+      el('thead', {}, [el('tr', {}, headers.map(function (h) { return el('th', {}, [h]); }))]),
+      el('tbody', {}, rows.map(function (row) {
+        return el('tr', {}, row.map(function (cell) { return el('td', {}, [cell]); }));
+      }))
+    ]);
+  }
+
+  function filterForm(page, params, fields) {
+    var inputs = {};
+    return el('form', {
+      onsubmit: function (e) {
+        e.preventDefault();
+        var next = {};
+        fields.forEach(function (field) { next[field] = inputs[field].value; });
+        location.hash = '#/' + page + query(next);
+      }
+    }, fields.map(function (field) {
+      inputs[field] = el('input', { name: field, placeholder: field, value: params[field] || '' });
+      return inputs[field];
+    }).concat([el('button', { type: 'submit' }, ['Filter'])]));
+  }
+
+  function pager(page, params, data) {
+    var links = [];
+    if (data.offset > 0) {
+      links.push(link(page, Object.assign({}, params, { offset: Math.max(0, data.offset - data.limit) }), 'Newer'));
+    }
+    if (data.next_offset !== undefined) {
+      links.push(link(page, Object.assign({}, params, { offset: data.next_offset }), 'Older'));
+    }
+    return el('div', { 'class': 'pager' }, links);
+  }
+
+  function lineChart(title, points, format) {
+    var width = 800, height = 180, pad = 30;
+    if (points.length === 0) {
+      return el('p', {}, ['No ' + title.toLowerCase() + ' recorded.']);
+    }
+    var max = Math.max.apply(null, points.map(function (p) { return p.y; })) || 1;
+    var step = points.length > 1 ? (width - 2 * pad) / (points.length - 1) : 0;
+    var coords = points.map(function (p, i) {
+      var x = pad + i * step;
+      var y = height - pad - (p.y / max) * (height - 2 * pad);
+      return x.toFixed(1) + ',' + y.toFixed(1);
+    });
+    return el('div', {}, [
+      el('h3', {}, [title]),
+      svg('svg', { 'class': 'chart', viewBox: '0 0 ' + width + ' ' + height, preserveAspectRatio: 'none' }, [
+        svg('line', { x1: pad, y1: height - pad, x2: width - pad, y2: height - pad }),
+        svg('line', { x1: pad, y1: pad, x2: width - pad, y2: pad }),
+        svg('text', { x: 0, y: pad + 4 }, [format(max)]),
+        svg('text', { x: 0, y: height - pad + 4 }, [format(0)]),
+        svg('polyline', { points: coords.join(' ') })
+      ])
+    ]);
+  }
+
+  var pages = {
+    runs: function (params) {
+      return api('runs', params).then(function (data) {
+        return [
+          el('h2', {}, ['Recent runs']),
+          filterForm('runs', params, ['repo', 'branch', 'sha']),
+          table(['Created', 'Repo', 'Branch', 'Sha', 'Build', 'Tags'], data.items.map(function (run) {
+            return [
+              link('run', { created: run.created }, time(run.created)),
+              link('runs', { repo: run.repo }, run.repo),
+              link('runs', { repo: run.repo, branch: run.branch }, run.branch),
+              run.sha.substring(0, 10),
+              run.build_id,
+              run.tags.join(' ')
+            ];
+          })),
+          pager('runs', params, data)
+        ];
+      });
+    },
+
+    run: function (params) {
+      return api('runs/' + encodeURIComponent(params.created)).then(function (data) {
+        var run = data.run;
+        return [
+          el('h2', {}, ['Run ' + time(run.created)]),
+          el('p', {}, [run.repo + ' ' + run.branch + ' ' + run.sha + (run.cmd ? ' — ' + run.cmd : '')]),
+          table(['Package', 'Result', 'Duration', 'Coverage'], data.results.map(function (result) {
+            return [
+              link('package', { name: result.package, repo: run.repo, branch: run.branch }, result.package),
+              outcome(result.result),
+              seconds(result.duration),
+              result.coverage ? percent(result.coverage) : ''
+            ];
+          }))
+        ];
+      });
+    },
+
+    package: function (params) {
+      var filter = { 'package': params.name, repo: params.repo, branch: params.branch, limit: 200 };
+      return api('packages/history', filter).then(function (data) {
+        var history = data.items.slice().reverse();
+        var results = history.filter(function (r) { return r.result !== 'flaky'; });
+        var passed = results.filter(function (r) { return r.result === 'pass'; });
+        return [
+          el('h2', {}, [params.name]),
+          el('p', {}, [(params.repo || 'all repos') + (params.branch ? ' ' + params.branch : '') +
+            ': ' + passed.length + ' of ' + results.length + ' runs passed']),
+          el('h3', {}, ['Timeline']),
+          el('div', { 'class': 'timeline' }, history.map(function (r) {
+            return el('span', { 'class': r.result, title: time(r.created) + ' ' + r.result });
+          })),
+          lineChart('Duration', results.map(function (r) { return { y: r.duration }; }), seconds),
+          lineChart('Coverage', passed.filter(function (r) { return r.coverage > 0; }).map(function (r) {
+            return { y: r.coverage };
+          }), percent)
+        ];
+      });
+    },
+
+    flaky: function (params) {
+      var filter = { repo: params.repo, branch: params.branch, since: params.since };
+      return Promise.all([api('flaky/packages', filter), api('flaky/tests', filter)]).then(function (data) {
+        function row(count) {
+          return [
+            link('package', { name: count['package'], repo: params.repo, branch: params.branch }, count['package']),
+            count.test || '',
+            count.flaky,
+            count.failed,
+            count.runs,
+            el('button', { onclick: function () { quarantine(params.repo, count['package'], count.test || ''); } }, ['Quarantine'])
+          ];
+        }
+        return [
+          el('h2', {}, ['Flaky leaderboard']),
+          filterForm('flaky', params, ['repo', 'branch', 'since']),
+          el('h3', {}, ['Packages']),
+          table(['Package', '', 'Flaky', 'Failed', 'Runs', ''], data[0].items.map(row)),
+          el('h3', {}, ['Tests']),
+          table(['Package', 'Test', 'Flaky', 'Failed', 'Runs', ''], data[1].items.map(row))
+        ];
+      });
+    },
+
+    quarantine: function (params) {
+      return api('quarantine', { repo: params.repo }).then(function (entries) {
+        var inputs = {};
+        var fields = ['repo', 'package', 'test', 'reason'];
+        return [
+          el('h2', {}, ['Quarantine']),
+          filterForm('quarantine', params, ['repo']),
+          table(['Repo', 'Package', 'Test', 'Reason', 'Issue', 'Since', ''], entries.map(function (q) {
+            var issue = /^https?:\/\//.test(q.issue || '') ? el('a', { href: q.issue }, [q.issue.replace(/^.*\//, '')]) : q.issue || '';
+            return [q.repo, q['package'], q.test, q.reason, issue, time(q.created), el('button', {
+              onclick: function () {
+                api('quarantine', { repo: q.repo, 'package': q['package'], test: q.test }, { method: 'DELETE' })
+                  .then(render, showError);
+              }
+            }, ['Remove'])];
+          })),
+          el('h3', {}, ['Add entry']),
+          el('form', {
+            onsubmit: function (e) {
+              e.preventDefault();
+              var entry = {};
+              fields.forEach(function (field) { entry[field] = inputs[field].value; });
+              api('quarantine', {}, { method: 'POST', body: JSON.stringify(entry) }).then(render, showError);
+            }
+          }, fields.map(function (field) {
+            inputs[field] = el('input', { name: field, placeholder: field, value: field === 'repo' ? params.repo || '' : '' });
+            return inputs[field];
+          }).concat([el('button', { type: 'submit' }, ['Quarantine'])]))
+        ];
+      });
+    }
+  };
+
+  function quarantine(repo, pkg, test) {
+    repo = repo || window.prompt('Repository');
+    if (!repo) {
+      return;
+    }
+    var reason = window.prompt('Reason for quarantining ' + (test ? test + ' in ' : '') + pkg, 'flaky');
+    if (reason === null) {
+      return;
+    }
+    api('quarantine', {}, { method: 'POST', body: JSON.stringify({ repo: repo, 'package': pkg, test: test, reason: reason }) })
+      .then(function () { location.hash = '#/quarantine' + query({ repo: repo }); }, showError);
+  }
+
+  function showError(err) {
+    main.insertBefore(el('p', { 'class': 'error' }, [err.message]), main.firstChild);
+  }
+
+  function render() {
+    var hash = location.hash.replace(/^#\/?/, '');
+    var split = hash.indexOf('?');
+    var name = split < 0 ? hash : hash.substring(0, split);
+    var params = {};
+    new URLSearchParams(split < 0 ? '' : hash.substring(split + 1)).forEach(function (value, key) {
+      params[key] = value;
+    });
+
+    var page = pages[name] || pages.runs;
+    page(params).then(function (nodes) {
+      main.textContent = '';
+      nodes.forEach(function (node) { main.appendChild(node); });
+    }).catch(function (err) {
+      main.textContent = '';
+      showError(err);
+    });
+  }
+
+  window.addEventListener('hashchange', render);
+  render();
+})();
+`
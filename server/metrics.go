@@ -0,0 +1,130 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/gocop/gocop"
+)
+
+// DefaultMetricsWindow is how far back stored history is aggregated into metrics
+const DefaultMetricsWindow = 7 * 24 * time.Hour
+
+// metric is a gauge reported for each label set of a family
+type metric struct {
+	name   string
+	help   string
+	values []sample
+}
+
+type sample struct {
+	labels []string
+	value  float64
+}
+
+func (s server) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	since := time.Now().Add(-s.config.MetricsWindow)
+	runs, err := s.store.GetRunCounts(since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	health, err := s.store.GetPackageHealth(since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = WriteMetrics(w, runs, health)
+}
+
+// WriteMetrics writes test health in the Prometheus text exposition format
+func WriteMetrics(w io.Writer, runs []gocop.RunCount, health []gocop.PackageHealth) error {
+	runCount := metric{name: "gocop_runs", help: "Number of stored runs in the metrics window."}
+	for _, count := range runs {
+		runCount.add(float64(count.Runs), "repo", count.Repo, "branch", count.Branch)
+	}
+
+	passRate := metric{name: "gocop_package_pass_rate", help: "Ratio of runs in which the package passed, ignoring skips."}
+	flakyRuns := metric{name: "gocop_package_flaky_runs", help: "Number of runs in which the package was flaky."}
+	failedRuns := metric{name: "gocop_package_failed_runs", help: "Number of runs in which the package failed."}
+	failing := metric{name: "gocop_package_failing", help: "Whether the package failed in the latest run of the branch."}
+	duration := metric{name: "gocop_package_duration_seconds_avg", help: "Average duration of the package."}
+	coverage := metric{name: "gocop_package_coverage_ratio_avg", help: "Average statement coverage of the package when passing."}
+	flakyPackages := metric{name: "gocop_flaky_packages", help: "Number of packages flaky at least once in the metrics window."}
+	failingPackages := metric{name: "gocop_failing_packages", help: "Number of packages failing in the latest run of the branch."}
+
+	type branch struct{ repo, name string }
+	branches := make([]branch, 0)
+	flaky := make(map[branch]int)
+	failed := make(map[branch]int)
+	for _, h := range health {
+		labels := []string{"repo", h.Repo, "branch", h.Branch, "package", h.Package}
+		b := branch{h.Repo, h.Branch}
+		if _, ok := flaky[b]; !ok {
+			branches = append(branches, b)
+			flaky[b], failed[b] = 0, 0
+		}
+
+		if h.Passed+h.Failed > 0 {
+			passRate.add(float64(h.Passed)/float64(h.Passed+h.Failed), labels...)
+			duration.add(h.Duration.Seconds(), labels...)
+		}
+		flakyRuns.add(float64(h.Flaky), labels...)
+		failedRuns.add(float64(h.Failed), labels...)
+		if h.Coverage > 0 {
+			coverage.add(h.Coverage, labels...)
+		}
+
+		latest := 0.0
+		if h.Latest == gocop.ResultFail {
+			latest = 1
+			failed[b]++
+		}
+		failing.add(latest, labels...)
+		if h.Flaky > 0 {
+			flaky[b]++
+		}
+	}
+	for _, b := range branches {
+		flakyPackages.add(float64(flaky[b]), "repo", b.repo, "branch", b.name)
+		failingPackages.add(float64(failed[b]), "repo", b.repo, "branch", b.name)
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, m := range []metric{runCount, flakyPackages, failingPackages, passRate, failedRuns, flakyRuns, failing, duration, coverage} {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+func (m *metric) add(value float64, labels ...string) {
+	m.values = append(m.values, sample{labels: labels, value: value})
+}
+
+func (m metric) write(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", m.name, m.help, m.name)
+	for _, s := range m.values {
+		pairs := make([]string, 0)
+		for i := 0; i+1 < len(s.labels); i += 2 {
+			pairs = append(pairs, s.labels[i]+`="`+escapeLabel(s.labels[i+1])+`"`)
+		}
+		fmt.Fprintf(w, "%s{%s} %s\n", m.name, strings.Join(pairs, ","), strconv.FormatFloat(s.value, 'g', -1, 64))
+	}
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,302 @@
+package server
+
+// OpenAPI describes the JSON API served by New
+const OpenAPI = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "gocop",
+    "description": "Test run and flakiness history stored by gocop",
+    "version": "1.0.0"
+  },
+  "paths": {
+    "/api/runs": {
+      "get": {
+        "summary": "List runs, most recent first",
+        "parameters": [
+          {"$ref": "#/components/parameters/repo"},
+          {"$ref": "#/components/parameters/branch"},
+          {"$ref": "#/components/parameters/sha"},
+          {"$ref": "#/components/parameters/kind"},
+          {"$ref": "#/components/parameters/since"},
+          {"$ref": "#/components/parameters/until"},
+          {"$ref": "#/components/parameters/limit"},
+          {"$ref": "#/components/parameters/offset"}
+        ],
+        "responses": {
+          "200": {"description": "A page of runs", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/RunPage"}}}},
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "summary": "Upload a run with raw test output or parsed results for storage",
+        "security": [{"bearer": []}],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Upload"}}}},
+        "responses": {
+          "201": {"description": "The stored run", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Run"}}}},
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/runs/{created}": {
+      "get": {
+        "summary": "Get a run and the results of its packages",
+        "parameters": [
+          {"name": "created", "in": "path", "required": true, "description": "Creation time of the run", "schema": {"type": "string", "format": "date-time"}}
+        ],
+        "responses": {
+          "200": {"description": "The run and its results", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/RunResults"}}}},
+          "400": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/packages/history": {
+      "get": {
+        "summary": "List the results of a package across runs, most recent first",
+        "parameters": [
+          {"name": "package", "in": "query", "required": true, "description": "Import path of the package", "schema": {"type": "string"}},
+          {"$ref": "#/components/parameters/repo"},
+          {"$ref": "#/components/parameters/branch"},
+          {"$ref": "#/components/parameters/sha"},
+          {"$ref": "#/components/parameters/kind"},
+          {"$ref": "#/components/parameters/since"},
+          {"$ref": "#/components/parameters/until"},
+          {"$ref": "#/components/parameters/limit"},
+          {"$ref": "#/components/parameters/offset"}
+        ],
+        "responses": {
+          "200": {"description": "A page of results", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ResultPage"}}}},
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/flaky/packages": {
+      "get": {
+        "summary": "Rank packages by how often they were flaky",
+        "parameters": [
+          {"$ref": "#/components/parameters/repo"},
+          {"$ref": "#/components/parameters/branch"},
+          {"$ref": "#/components/parameters/since"},
+          {"$ref": "#/components/parameters/until"},
+          {"$ref": "#/components/parameters/limit"},
+          {"$ref": "#/components/parameters/offset"}
+        ],
+        "responses": {
+          "200": {"description": "A page of flaky packages", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/FlakyPage"}}}},
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/flaky/tests": {
+      "get": {
+        "summary": "Rank tests by how often they were flaky",
+        "parameters": [
+          {"$ref": "#/components/parameters/repo"},
+          {"$ref": "#/components/parameters/branch"},
+          {"$ref": "#/components/parameters/since"},
+          {"$ref": "#/components/parameters/until"},
+          {"$ref": "#/components/parameters/limit"},
+          {"$ref": "#/components/parameters/offset"}
+        ],
+        "responses": {
+          "200": {"description": "A page of flaky tests", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/FlakyPage"}}}},
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/coverage": {
+      "get": {
+        "summary": "List coverage of a package, or the average of all packages, across runs, most recent first",
+        "parameters": [
+          {"name": "package", "in": "query", "description": "Import path of the package", "schema": {"type": "string"}},
+          {"$ref": "#/components/parameters/repo"},
+          {"$ref": "#/components/parameters/branch"},
+          {"$ref": "#/components/parameters/since"},
+          {"$ref": "#/components/parameters/until"},
+          {"$ref": "#/components/parameters/limit"},
+          {"$ref": "#/components/parameters/offset"}
+        ],
+        "responses": {
+          "200": {"description": "A page of coverage points", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/CoveragePage"}}}},
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/quarantine": {
+      "get": {
+        "summary": "List quarantined packages and tests",
+        "parameters": [
+          {"name": "repo", "in": "query", "description": "Repository name, all repositories when omitted", "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "Quarantine entries", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Quarantine"}}}}}
+        }
+      },
+      "post": {
+        "summary": "Quarantine a package, or a single test when test is set",
+        "security": [{"bearer": []}],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Quarantine"}}}},
+        "responses": {
+          "201": {"description": "The quarantine entry", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Quarantine"}}}},
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "delete": {
+        "summary": "Remove a quarantine entry",
+        "security": [{"bearer": []}],
+        "parameters": [
+          {"name": "repo", "in": "query", "required": true, "schema": {"type": "string"}},
+          {"name": "package", "in": "query", "required": true, "schema": {"type": "string"}},
+          {"name": "test", "in": "query", "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "204": {"description": "The entry was removed"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/openapi.json": {
+      "get": {
+        "summary": "This document",
+        "responses": {"200": {"description": "OpenAPI description of the API"}}
+      }
+    }
+  },
+  "components": {
+    "securitySchemes": {
+      "bearer": {"type": "http", "scheme": "bearer"}
+    },
+    "parameters": {
+      "repo": {"name": "repo", "in": "query", "description": "Repository name", "schema": {"type": "string"}},
+      "branch": {"name": "branch", "in": "query", "description": "Branch name", "schema": {"type": "string"}},
+      "sha": {"name": "sha", "in": "query", "description": "Git sha of the run", "schema": {"type": "string"}},
+      "kind": {"name": "kind", "in": "query", "description": "Kind of the run, CI builds unless set", "schema": {"type": "string", "enum": ["ci", "verify"], "default": "ci"}},
+      "since": {"name": "since", "in": "query", "description": "Earliest run creation time, inclusive", "schema": {"type": "string", "format": "date-time"}},
+      "until": {"name": "until", "in": "query", "description": "Latest run creation time, exclusive", "schema": {"type": "string", "format": "date-time"}},
+      "limit": {"name": "limit", "in": "query", "description": "Page size", "schema": {"type": "integer", "minimum": 1, "maximum": 500, "default": 50}},
+      "offset": {"name": "offset", "in": "query", "description": "Number of items to skip", "schema": {"type": "integer", "minimum": 0, "default": 0}}
+    },
+    "responses": {
+      "Error": {"description": "The request failed", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}}
+    },
+    "schemas": {
+      "Error": {
+        "type": "object",
+        "properties": {"error": {"type": "string"}}
+      },
+      "Run": {
+        "type": "object",
+        "properties": {
+          "created": {"type": "string", "format": "date-time"},
+          "build_id": {"type": "integer"},
+          "repo": {"type": "string"},
+          "branch": {"type": "string"},
+          "sha": {"type": "string"},
+          "cmd": {"type": "string"},
+          "benchmark": {"type": "boolean"},
+          "short": {"type": "boolean"},
+          "race": {"type": "boolean"},
+          "tags": {"type": "array", "items": {"type": "string"}},
+          "duration": {"type": "number", "description": "Seconds"},
+          "kind": {"type": "string", "enum": ["ci", "verify"], "description": "ci for CI builds, verify for gocop verify"},
+          "metadata": {"type": "object", "additionalProperties": {"type": "string"}, "description": "Environment of the run, such as go_version, goos, goarch, gomaxprocs and runner"},
+          "flags": {"$ref": "#/components/schemas/TestFlags"}
+        }
+      },
+      "TestFlags": {
+        "type": "object",
+        "description": "Configuration parsed from the go test command line of a run",
+        "properties": {
+          "race": {"type": "boolean"},
+          "short": {"type": "boolean"},
+          "bench": {"type": "string"},
+          "tags": {"type": "array", "items": {"type": "string"}},
+          "count": {"type": "integer"},
+          "cpu": {"type": "string"},
+          "timeout": {"type": "string"},
+          "run": {"type": "string"},
+          "shuffle": {"type": "string"},
+          "coverpkg": {"type": "array", "items": {"type": "string"}},
+          "packages": {"type": "array", "items": {"type": "string"}}
+        }
+      },
+      "Result": {
+        "type": "object",
+        "properties": {
+          "created": {"type": "string", "format": "date-time"},
+          "package": {"type": "string"},
+          "result": {"type": "string", "enum": ["pass", "fail", "flaky", "skip"]},
+          "duration": {"type": "number", "description": "Seconds"},
+          "coverage": {"type": "number", "description": "Percentage of statements covered"},
+          "shuffle": {"type": "integer", "format": "int64", "description": "Seed of the test order with -shuffle"}
+        }
+      },
+      "Upload": {
+        "type": "object",
+        "properties": {
+          "run": {"$ref": "#/components/schemas/Run"},
+          "output": {"type": "string", "description": "Raw go test, go test -json or JUnit XML output"},
+          "retests": {"type": "array", "items": {"type": "string"}, "description": "Raw output of retests used to detect flaky packages"},
+          "results": {"type": "array", "items": {"type": "object"}, "description": "Parsed package results, used when output is omitted"},
+          "flaky": {"type": "array", "items": {"type": "object"}, "description": "Parsed flaky packages, used when retests are omitted"}
+        }
+      },
+      "RunResults": {
+        "type": "object",
+        "properties": {
+          "run": {"$ref": "#/components/schemas/Run"},
+          "results": {"type": "array", "items": {"$ref": "#/components/schemas/Result"}}
+        }
+      },
+      "FlakyCount": {
+        "type": "object",
+        "properties": {
+          "package": {"type": "string"},
+          "test": {"type": "string"},
+          "flaky": {"type": "integer", "description": "Runs in which it was flaky"},
+          "failed": {"type": "integer", "description": "Runs in which it failed"},
+          "runs": {"type": "integer", "description": "Runs of the package, which for a test are the runs of its package"}
+        }
+      },
+      "Quarantine": {
+        "type": "object",
+        "required": ["repo", "package"],
+        "properties": {
+          "repo": {"type": "string"},
+          "package": {"type": "string"},
+          "test": {"type": "string", "description": "Test name, empty when the whole package is quarantined"},
+          "reason": {"type": "string"},
+          "issue": {"type": "string", "description": "URL of the issue tracking the flaky package or test"},
+          "created": {"type": "string", "format": "date-time", "readOnly": true}
+        }
+      },
+      "CoveragePoint": {
+        "type": "object",
+        "properties": {
+          "created": {"type": "string", "format": "date-time"},
+          "sha": {"type": "string"},
+          "coverage": {"type": "number", "description": "Percentage of statements covered"}
+        }
+      },
+      "Page": {
+        "type": "object",
+        "properties": {
+          "limit": {"type": "integer"},
+          "offset": {"type": "integer"},
+          "next_offset": {"type": "integer", "description": "Offset of the next page, omitted on the last page"}
+        }
+      },
+      "RunPage": {"allOf": [{"$ref": "#/components/schemas/Page"}, {"type": "object", "properties": {"items": {"type": "array", "items": {"$ref": "#/components/schemas/Run"}}}}]},
+      "ResultPage": {"allOf": [{"$ref": "#/components/schemas/Page"}, {"type": "object", "properties": {"items": {"type": "array", "items": {"$ref": "#/components/schemas/Result"}}}}]},
+      "FlakyPage": {"allOf": [{"$ref": "#/components/schemas/Page"}, {"type": "object", "properties": {"items": {"type": "array", "items": {"$ref": "#/components/schemas/FlakyCount"}}}}]},
+      "CoveragePage": {"allOf": [{"$ref": "#/components/schemas/Page"}, {"type": "object", "properties": {"items": {"type": "array", "items": {"$ref": "#/components/schemas/CoveragePoint"}}}}]}
+    }
+  }
+}`
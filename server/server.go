@@ -0,0 +1,621 @@
+package server
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/gocop/gocop"
+)
+
+// MaxUploadSize limits the size of an uploaded run
+const MaxUploadSize = 256 << 20
+
+const (
+	// DefaultLimit is the page size used when a request does not set a limit
+	DefaultLimit = 50
+	// MaxLimit is the largest page size a request may ask for
+	MaxLimit = 500
+)
+
+// Store provides the stored run history served by the API
+type Store interface {
+	FindRuns(filter gocop.RunFilter) ([]gocop.TestRun, error)
+	FindRun(created time.Time) (gocop.TestRun, error)
+	GetResults(created time.Time) ([]gocop.TestResult, error)
+	GetPackageHistory(filter gocop.RunFilter, pkg string) ([]gocop.TestResult, error)
+	GetFlakiestPackages(filter gocop.RunFilter) ([]gocop.FlakyCount, error)
+	GetFlakiestTests(filter gocop.RunFilter) ([]gocop.FlakyCount, error)
+	GetCoverageTrend(filter gocop.RunFilter, pkg string) ([]gocop.CoveragePoint, error)
+	GetQuarantined(repo string) ([]gocop.Quarantine, error)
+	InsertQuarantine(q gocop.Quarantine) (gocop.Quarantine, error)
+	DeleteQuarantine(repo, pkg, test string) (bool, error)
+	StoreRun(run gocop.TestRun, results gocop.PackageResults, flaky gocop.FlakyPackages) error
+	GetRunCounts(since time.Time) ([]gocop.RunCount, error)
+	GetPackageHealth(since time.Time) ([]gocop.PackageHealth, error)
+}
+
+// DBStore serves the API from the database
+type DBStore struct {
+	DB *sql.DB
+}
+
+// FindRuns lists runs matching a filter
+func (s DBStore) FindRuns(filter gocop.RunFilter) ([]gocop.TestRun, error) {
+	return gocop.FindRuns(s.DB, filter)
+}
+
+// FindRun retrieves a single run
+func (s DBStore) FindRun(created time.Time) (gocop.TestRun, error) {
+	return gocop.FindRun(s.DB, created)
+}
+
+// GetResults retrieves the package results of a run
+func (s DBStore) GetResults(created time.Time) ([]gocop.TestResult, error) {
+	return gocop.GetResults(s.DB, created)
+}
+
+// GetPackageHistory retrieves the results of a package across runs
+func (s DBStore) GetPackageHistory(filter gocop.RunFilter, pkg string) ([]gocop.TestResult, error) {
+	return gocop.GetPackageHistory(s.DB, filter, pkg)
+}
+
+// GetFlakiestPackages ranks packages by how often they were flaky
+func (s DBStore) GetFlakiestPackages(filter gocop.RunFilter) ([]gocop.FlakyCount, error) {
+	return gocop.GetFlakiestPackages(s.DB, filter)
+}
+
+// GetFlakiestTests ranks tests by how often they were flaky
+func (s DBStore) GetFlakiestTests(filter gocop.RunFilter) ([]gocop.FlakyCount, error) {
+	return gocop.GetFlakiestTests(s.DB, filter)
+}
+
+// GetCoverageTrend retrieves coverage across runs
+func (s DBStore) GetCoverageTrend(filter gocop.RunFilter, pkg string) ([]gocop.CoveragePoint, error) {
+	return gocop.GetCoverageTrend(s.DB, filter, pkg)
+}
+
+// GetQuarantined lists quarantine entries
+func (s DBStore) GetQuarantined(repo string) ([]gocop.Quarantine, error) {
+	return gocop.GetQuarantined(s.DB, repo)
+}
+
+// InsertQuarantine quarantines a package or test
+func (s DBStore) InsertQuarantine(q gocop.Quarantine) (gocop.Quarantine, error) {
+	return gocop.InsertQuarantine(s.DB, q)
+}
+
+// DeleteQuarantine removes a quarantine entry
+func (s DBStore) DeleteQuarantine(repo, pkg, test string) (bool, error) {
+	return gocop.DeleteQuarantine(s.DB, repo, pkg, test)
+}
+
+// StoreRun inserts an uploaded run
+func (s DBStore) StoreRun(run gocop.TestRun, results gocop.PackageResults, flaky gocop.FlakyPackages) error {
+	return gocop.StoreRun(s.DB, run, results, flaky)
+}
+
+// GetRunCounts counts the runs of each branch
+func (s DBStore) GetRunCounts(since time.Time) ([]gocop.RunCount, error) {
+	return gocop.GetRunCounts(s.DB, since)
+}
+
+// GetPackageHealth totals the results of each package
+func (s DBStore) GetPackageHealth(since time.Time) ([]gocop.PackageHealth, error) {
+	return gocop.GetPackageHealth(s.DB, since)
+}
+
+// Run is the JSON representation of a stored run
+type Run struct {
+	Created   time.Time `json:"created"`
+	BuildID   int64     `json:"build_id"`
+	Repo      string    `json:"repo"`
+	Branch    string    `json:"branch"`
+	Sha       string    `json:"sha"`
+	Command   string    `json:"cmd"`
+	Benchmark bool      `json:"benchmark"`
+	Short     bool      `json:"short"`
+	Race      bool      `json:"race"`
+	Tags      []string  `json:"tags"`
+	Duration  float64   `json:"duration"`
+	Kind      string    `json:"kind,omitempty"`
+	// Metadata describes the environment of the run, such as its Go version and CI runner
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Flags is the configuration parsed from the go test command line of the run
+	Flags *gocop.TestFlags `json:"flags,omitempty"`
+}
+
+// TestRun converts an uploaded run for storage, defaulting its creation time to now
+func (r Run) TestRun() gocop.TestRun {
+	run := gocop.TestRun{
+		Created:   r.Created,
+		BuildID:   r.BuildID,
+		Repo:      r.Repo,
+		Branch:    r.Branch,
+		Sha:       r.Sha,
+		Command:   r.Command,
+		Benchmark: r.Benchmark,
+		Short:     r.Short,
+		Race:      r.Race,
+		Tags:      r.Tags,
+		Duration:  time.Duration(r.Duration * float64(time.Second)),
+		Kind:      r.Kind,
+		Metadata:  r.Metadata,
+	}
+	// clients other than gocop push may only record the command line
+	if r.Flags != nil {
+		run.Flags = *r.Flags
+	} else if flags, err := gocop.ParseTestCommand(r.Command); err == nil {
+		run.Flags = flags
+	}
+	if run.Created.IsZero() {
+		run.Created = time.Now().UTC()
+	}
+	return run
+}
+
+// Upload is a run pushed to the server for storage, with either raw test output or parsed results
+type Upload struct {
+	Run     Run                  `json:"run"`
+	Output  string               `json:"output,omitempty"`
+	Retests []string             `json:"retests,omitempty"`
+	Results gocop.PackageResults `json:"results,omitempty"`
+	Flaky   gocop.FlakyPackages  `json:"flaky,omitempty"`
+}
+
+// Result is the JSON representation of a stored package result, with durations in seconds and coverage as a percentage
+type Result struct {
+	Created  time.Time `json:"created"`
+	Package  string    `json:"package"`
+	Result   string    `json:"result"`
+	Duration float64   `json:"duration"`
+	Coverage float64   `json:"coverage"`
+	Shuffle  int64     `json:"shuffle,omitempty"`
+}
+
+// RunResults is a run with the results of its packages
+type RunResults struct {
+	Run     Run      `json:"run"`
+	Results []Result `json:"results"`
+}
+
+// Page is a page of items from a list endpoint
+type Page struct {
+	Items      interface{} `json:"items"`
+	Limit      int         `json:"limit"`
+	Offset     int         `json:"offset"`
+	NextOffset *int        `json:"next_offset,omitempty"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// Config configures the server
+type Config struct {
+	// Tokens are the bearer tokens accepted for uploads and quarantine changes, which are disabled without any
+	Tokens []string
+	// MetricsWindow is how far back stored history is aggregated into metrics
+	MetricsWindow time.Duration
+}
+
+type server struct {
+	store  Store
+	config Config
+}
+
+// endpoint handles a request, returning a value to be encoded as JSON
+type endpoint func(r *http.Request) (interface{}, error)
+
+// New returns a handler serving the dashboard, its JSON API and metrics over a store
+func New(store Store, config Config) http.Handler {
+	if config.MetricsWindow == 0 {
+		config.MetricsWindow = DefaultMetricsWindow
+	}
+	s := server{store: store, config: config}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", serveIndex)
+	mux.HandleFunc("/static/", serveStatic)
+	mux.HandleFunc("/metrics", s.serveMetrics)
+	mux.HandleFunc("/api/runs", s.handle(map[string]endpoint{
+		http.MethodGet:  s.runs,
+		http.MethodHead: s.runs,
+		http.MethodPost: s.authorized(s.upload),
+	}))
+	mux.HandleFunc("/api/runs/", s.get(s.run))
+	mux.HandleFunc("/api/packages/history", s.get(s.packageHistory))
+	mux.HandleFunc("/api/flaky/packages", s.get(s.flakyPackages))
+	mux.HandleFunc("/api/flaky/tests", s.get(s.flakyTests))
+	mux.HandleFunc("/api/coverage", s.get(s.coverage))
+	mux.HandleFunc("/api/quarantine", s.handle(map[string]endpoint{
+		http.MethodGet:    s.quarantined,
+		http.MethodPost:   s.authorized(s.quarantine),
+		http.MethodDelete: s.authorized(s.unquarantine),
+	}))
+	mux.HandleFunc("/api/openapi.json", s.get(func(r *http.Request) (interface{}, error) {
+		return json.RawMessage(OpenAPI), nil
+	}))
+
+	return mux
+}
+
+// statusError is an error with the HTTP status it should be reported with
+type statusError struct {
+	status int
+	err    error
+}
+
+func (e statusError) Error() string {
+	return e.err.Error()
+}
+
+func badRequest(format string, a ...interface{}) error {
+	return statusError{status: http.StatusBadRequest, err: fmt.Errorf(format, a...)}
+}
+
+// get adapts an endpoint into a GET handler
+func (s server) get(fn endpoint) http.HandlerFunc {
+	return s.handle(map[string]endpoint{http.MethodGet: fn, http.MethodHead: fn})
+}
+
+// handle dispatches requests to the endpoint for their method
+func (s server) handle(endpoints map[string]endpoint) http.HandlerFunc {
+	allowed := make([]string, 0)
+	for method := range endpoints {
+		allowed = append(allowed, method)
+	}
+	sort.Strings(allowed)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		fn, ok := endpoints[r.Method]
+		if !ok {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+			return
+		}
+
+		v, err := fn(r)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if e, ok := err.(statusError); ok {
+				status = e.status
+			}
+			writeJSON(w, status, apiError{Error: err.Error()})
+			return
+		}
+
+		switch {
+		case v == nil:
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost:
+			writeJSON(w, http.StatusCreated, v)
+		default:
+			writeJSON(w, http.StatusOK, v)
+		}
+	}
+}
+
+// authorized requires requests to an endpoint to bear one of the server's tokens
+func (s server) authorized(fn endpoint) endpoint {
+	return func(r *http.Request) (interface{}, error) {
+		if len(s.config.Tokens) == 0 {
+			return nil, statusError{status: http.StatusForbidden, err: errors.New("changes are disabled as the server has no tokens")}
+		}
+
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		for _, t := range s.config.Tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+				return fn(r)
+			}
+		}
+		return nil, statusError{status: http.StatusUnauthorized, err: errors.New("invalid or missing bearer token")}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (s server) runs(r *http.Request) (interface{}, error) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		return nil, err
+	}
+
+	runs, err := s.store.FindRuns(more(filter))
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Run, 0)
+	for _, run := range runs {
+		items = append(items, newRun(run))
+	}
+	return newPage(filter, items, len(items) > filter.Limit), nil
+}
+
+func (s server) upload(r *http.Request) (interface{}, error) {
+	var upload Upload
+	err := json.NewDecoder(http.MaxBytesReader(nil, r.Body, MaxUploadSize)).Decode(&upload)
+	if err != nil {
+		return nil, badRequest("invalid upload: %v", err)
+	}
+
+	results := upload.Results
+	if upload.Output != "" {
+		results, err = gocop.ReadResults(strings.NewReader(upload.Output))
+		if err != nil {
+			return nil, badRequest("unable to parse output: %v", err)
+		}
+	}
+
+	flaky := upload.Flaky
+	if len(upload.Retests) > 0 {
+		readers := make([]io.Reader, 0)
+		for _, retest := range upload.Retests {
+			readers = append(readers, strings.NewReader(retest))
+		}
+		flaky, err = gocop.FlakyReport(readers...)
+		if err != nil {
+			return nil, badRequest("unable to parse retests: %v", err)
+		}
+	}
+
+	if len(results) == 0 && len(flaky) == 0 {
+		return nil, badRequest("upload contains no test results")
+	}
+	if !validKind(upload.Run.Kind) {
+		return nil, badRequest("kind must be one of %s|%s: %q", gocop.RunKindCI, gocop.RunKindVerify, upload.Run.Kind)
+	}
+
+	run := upload.Run.TestRun()
+	err = s.store.StoreRun(run, results, flaky)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRun(run), nil
+}
+
+func (s server) run(r *http.Request) (interface{}, error) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/runs/")
+	created, err := time.Parse(time.RFC3339Nano, id)
+	if err != nil {
+		return nil, badRequest("run must be identified by its RFC 3339 creation time: %q", id)
+	}
+
+	run, err := s.store.FindRun(created)
+	if err == sql.ErrNoRows {
+		return nil, statusError{status: http.StatusNotFound, err: fmt.Errorf("no run created at %s", id)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := s.store.GetResults(run.Created)
+	if err != nil {
+		return nil, err
+	}
+
+	return RunResults{Run: newRun(run), Results: newResults(results)}, nil
+}
+
+func (s server) packageHistory(r *http.Request) (interface{}, error) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		return nil, err
+	}
+	pkg := r.URL.Query().Get("package")
+	if pkg == "" {
+		return nil, badRequest("package is required")
+	}
+
+	results, err := s.store.GetPackageHistory(more(filter), pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	items := newResults(results)
+	return newPage(filter, items, len(items) > filter.Limit), nil
+}
+
+func (s server) flakyPackages(r *http.Request) (interface{}, error) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.store.GetFlakiestPackages(more(filter))
+	if err != nil {
+		return nil, err
+	}
+	return newPage(filter, counts, len(counts) > filter.Limit), nil
+}
+
+func (s server) flakyTests(r *http.Request) (interface{}, error) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.store.GetFlakiestTests(more(filter))
+	if err != nil {
+		return nil, err
+	}
+	return newPage(filter, counts, len(counts) > filter.Limit), nil
+}
+
+func (s server) coverage(r *http.Request) (interface{}, error) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		return nil, err
+	}
+
+	points, err := s.store.GetCoverageTrend(more(filter), r.URL.Query().Get("package"))
+	if err != nil {
+		return nil, err
+	}
+	return newPage(filter, points, len(points) > filter.Limit), nil
+}
+
+func (s server) quarantined(r *http.Request) (interface{}, error) {
+	return s.store.GetQuarantined(r.URL.Query().Get("repo"))
+}
+
+func (s server) quarantine(r *http.Request) (interface{}, error) {
+	var q gocop.Quarantine
+	err := json.NewDecoder(r.Body).Decode(&q)
+	if err != nil {
+		return nil, badRequest("invalid quarantine entry: %v", err)
+	}
+	if q.Repo == "" || q.Package == "" {
+		return nil, badRequest("repo and package are required")
+	}
+
+	return s.store.InsertQuarantine(q)
+}
+
+func (s server) unquarantine(r *http.Request) (interface{}, error) {
+	query := r.URL.Query()
+	if query.Get("repo") == "" || query.Get("package") == "" {
+		return nil, badRequest("repo and package are required")
+	}
+
+	ok, err := s.store.DeleteQuarantine(query.Get("repo"), query.Get("package"), query.Get("test"))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, statusError{status: http.StatusNotFound, err: fmt.Errorf("%s is not quarantined", query.Get("package"))}
+	}
+	return nil, nil
+}
+
+// parseFilter reads the run filter and pagination shared by list endpoints from the query string
+func parseFilter(r *http.Request) (gocop.RunFilter, error) {
+	query := r.URL.Query()
+	filter := gocop.RunFilter{
+		Repo:   query.Get("repo"),
+		Branch: query.Get("branch"),
+		Sha:    query.Get("sha"),
+		Kind:   query.Get("kind"),
+		Limit:  DefaultLimit,
+	}
+	if !validKind(filter.Kind) {
+		return filter, badRequest("kind must be one of %s|%s: %q", gocop.RunKindCI, gocop.RunKindVerify, filter.Kind)
+	}
+
+	var err error
+	for name, t := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := query.Get(name); v != "" {
+			*t, err = time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return filter, badRequest("%s must be an RFC 3339 time: %q", name, v)
+			}
+		}
+	}
+
+	for name, n := range map[string]*int{"limit": &filter.Limit, "offset": &filter.Offset} {
+		if v := query.Get(name); v != "" {
+			*n, err = strconv.Atoi(v)
+			if err != nil || *n < 0 {
+				return filter, badRequest("%s must be a non-negative integer: %q", name, v)
+			}
+		}
+	}
+	if filter.Limit == 0 || filter.Limit > MaxLimit {
+		return filter, badRequest("limit must be between 1 and %d", MaxLimit)
+	}
+
+	return filter, nil
+}
+
+// more requests one item beyond the page to learn whether another page follows
+func more(filter gocop.RunFilter) gocop.RunFilter {
+	filter.Limit++
+	return filter
+}
+
+func newPage(filter gocop.RunFilter, items interface{}, hasMore bool) Page {
+	page := Page{Items: items, Limit: filter.Limit, Offset: filter.Offset}
+	if hasMore {
+		next := filter.Offset + filter.Limit
+		page.NextOffset = &next
+		page.Items = trim(items, filter.Limit)
+	}
+	return page
+}
+
+func trim(items interface{}, n int) interface{} {
+	switch v := items.(type) {
+	case []Run:
+		return v[:n]
+	case []Result:
+		return v[:n]
+	case []gocop.FlakyCount:
+		return v[:n]
+	case []gocop.CoveragePoint:
+		return v[:n]
+	}
+	return items
+}
+
+// validKind reports whether a run kind is known, an empty kind selecting CI runs
+func validKind(kind string) bool {
+	return kind == "" || kind == gocop.RunKindCI || kind == gocop.RunKindVerify
+}
+
+func newRun(run gocop.TestRun) Run {
+	tags := run.Tags
+	if tags == nil {
+		tags = make([]string, 0)
+	}
+	var flags *gocop.TestFlags
+	if !reflect.DeepEqual(run.Flags, gocop.TestFlags{}) {
+		flags = &run.Flags
+	}
+	return Run{
+		Created:   run.Created,
+		BuildID:   run.BuildID,
+		Repo:      run.Repo,
+		Branch:    run.Branch,
+		Sha:       run.Sha,
+		Command:   run.Command,
+		Benchmark: run.Benchmark,
+		Short:     run.Short,
+		Race:      run.Race,
+		Tags:      tags,
+		Duration:  run.Duration.Seconds(),
+		Kind:      run.Kind,
+		Metadata:  run.Metadata,
+		Flags:     flags,
+	}
+}
+
+func newResults(results []gocop.TestResult) []Result {
+	items := make([]Result, 0)
+	for _, result := range results {
+		items = append(items, Result{
+			Created:  result.Created,
+			Package:  result.Package,
+			Result:   result.Result,
+			Duration: result.Duration.Seconds(),
+			// stored coverage is a fraction rather than a percentage
+			Coverage: result.Coverage * 100,
+			Shuffle:  result.Shuffle,
+		})
+	}
+	return items
+}
@@ -0,0 +1,279 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/gocop/gocop"
+	"github.com/poy/onpar"
+	"github.com/poy/onpar/expect"
+	"github.com/poy/onpar/matchers"
+)
+
+type fakeStore struct {
+	filter     gocop.RunFilter
+	pkg        string
+	runs       []gocop.TestRun
+	quarantine []gocop.Quarantine
+	stored     gocop.PackageResults
+	flaky      gocop.FlakyPackages
+}
+
+func (f *fakeStore) FindRuns(filter gocop.RunFilter) ([]gocop.TestRun, error) {
+	f.filter = filter
+	runs := f.runs[filter.Offset:]
+	if len(runs) > filter.Limit {
+		runs = runs[:filter.Limit]
+	}
+	return runs, nil
+}
+
+func (f *fakeStore) FindRun(created time.Time) (gocop.TestRun, error) {
+	for _, run := range f.runs {
+		if run.Created.Equal(created) {
+			return run, nil
+		}
+	}
+	return gocop.TestRun{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) GetResults(created time.Time) ([]gocop.TestResult, error) {
+	return []gocop.TestResult{{Created: created, Package: "example.com/pkg", Result: gocop.ResultPass, Duration: 1500 * time.Millisecond, Coverage: 0.5}}, nil
+}
+
+func (f *fakeStore) GetPackageHistory(filter gocop.RunFilter, pkg string) ([]gocop.TestResult, error) {
+	f.filter, f.pkg = filter, pkg
+	return nil, nil
+}
+
+func (f *fakeStore) GetFlakiestPackages(filter gocop.RunFilter) ([]gocop.FlakyCount, error) {
+	f.filter = filter
+	return []gocop.FlakyCount{{Package: "example.com/flaky", Flaky: 2, Failed: 3, Runs: 10}}, nil
+}
+
+func (f *fakeStore) GetFlakiestTests(filter gocop.RunFilter) ([]gocop.FlakyCount, error) {
+	f.filter = filter
+	return nil, nil
+}
+
+func (f *fakeStore) GetCoverageTrend(filter gocop.RunFilter, pkg string) ([]gocop.CoveragePoint, error) {
+	f.filter, f.pkg = filter, pkg
+	return nil, nil
+}
+
+func (f *fakeStore) GetQuarantined(repo string) ([]gocop.Quarantine, error) {
+	return f.quarantine, nil
+}
+
+func (f *fakeStore) InsertQuarantine(q gocop.Quarantine) (gocop.Quarantine, error) {
+	f.quarantine = append(f.quarantine, q)
+	return q, nil
+}
+
+func (f *fakeStore) DeleteQuarantine(repo, pkg, test string) (bool, error) {
+	for i, q := range f.quarantine {
+		if q.Repo == repo && q.Package == pkg && q.Test == test {
+			f.quarantine = append(f.quarantine[:i], f.quarantine[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeStore) StoreRun(run gocop.TestRun, results gocop.PackageResults, flaky gocop.FlakyPackages) error {
+	f.runs = append(f.runs, run)
+	f.stored, f.flaky = results, flaky
+	return nil
+}
+
+func (f *fakeStore) GetRunCounts(since time.Time) ([]gocop.RunCount, error) {
+	return []gocop.RunCount{{Repo: "gocop", Branch: "master", Runs: 3}}, nil
+}
+
+func (f *fakeStore) GetPackageHealth(since time.Time) ([]gocop.PackageHealth, error) {
+	return []gocop.PackageHealth{{Repo: "gocop", Branch: "master", Package: "example.com/flaky", Passed: 3, Failed: 1, Flaky: 1, Latest: gocop.ResultFail}}, nil
+}
+
+func TestServer(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	runs := make([]gocop.TestRun, 0)
+	for i := 0; i < 3; i++ {
+		runs = append(runs, gocop.TestRun{Created: created.Add(-time.Duration(i) * time.Hour), Repo: "gocop", Branch: "master"})
+	}
+
+	type fixture struct {
+		expect expect.Expectation
+		store  *fakeStore
+		get    func(target string) *httptest.ResponseRecorder
+		do     func(method, target, body string, header ...string) *httptest.ResponseRecorder
+	}
+
+	o.BeforeEach(func(t *testing.T) fixture {
+		store := &fakeStore{runs: runs}
+		handler := New(store, Config{Tokens: []string{"secret"}})
+		return fixture{
+			expect: expect.New(t),
+			store:  store,
+			get: func(target string) *httptest.ResponseRecorder {
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+				return w
+			},
+			do: func(method, target, body string, header ...string) *httptest.ResponseRecorder {
+				w := httptest.NewRecorder()
+				r := httptest.NewRequest(method, target, strings.NewReader(body))
+				for i := 0; i+1 < len(header); i += 2 {
+					r.Header.Set(header[i], header[i+1])
+				}
+				handler.ServeHTTP(w, r)
+				return w
+			},
+		}
+	})
+
+	o.Spec("filters and pages through runs", func(f fixture) {
+		w := f.get("/api/runs?repo=gocop&branch=master&since=2020-01-01T00:00:00Z&limit=2")
+		f.expect(w.Code).To(matchers.Equal(http.StatusOK))
+		f.expect(f.store.filter.Repo).To(matchers.Equal("gocop"))
+		f.expect(f.store.filter.Since.Year()).To(matchers.Equal(2020))
+
+		var page struct {
+			Items      []Run `json:"items"`
+			NextOffset *int  `json:"next_offset"`
+		}
+		f.expect(json.Unmarshal(w.Body.Bytes(), &page)).To(matchers.BeNil())
+		f.expect(page.Items).To(matchers.HaveLen(2))
+		f.expect(*page.NextOffset).To(matchers.Equal(2))
+
+		w = f.get("/api/runs?offset=2&limit=2")
+		page.NextOffset = nil
+		f.expect(json.Unmarshal(w.Body.Bytes(), &page)).To(matchers.BeNil())
+		f.expect(page.Items).To(matchers.HaveLen(1))
+		f.expect(page.NextOffset).To(matchers.BeNil())
+	})
+
+	o.Spec("returns a run with its results", func(f fixture) {
+		w := f.get("/api/runs/2020-01-02T03:04:05Z")
+		f.expect(w.Code).To(matchers.Equal(http.StatusOK))
+
+		var run RunResults
+		f.expect(json.Unmarshal(w.Body.Bytes(), &run)).To(matchers.BeNil())
+		f.expect(run.Run.Repo).To(matchers.Equal("gocop"))
+		f.expect(run.Results[0].Duration).To(matchers.Equal(1.5))
+		f.expect(run.Results[0].Coverage).To(matchers.Equal(50.0))
+
+		f.expect(f.get("/api/runs/2019-01-01T00:00:00Z").Code).To(matchers.Equal(http.StatusNotFound))
+		f.expect(f.get("/api/runs/yesterday").Code).To(matchers.Equal(http.StatusBadRequest))
+	})
+
+	o.Spec("requires a package for its history", func(f fixture) {
+		f.expect(f.get("/api/packages/history").Code).To(matchers.Equal(http.StatusBadRequest))
+		f.expect(f.get("/api/packages/history?package=example.com/pkg").Code).To(matchers.Equal(http.StatusOK))
+		f.expect(f.store.pkg).To(matchers.Equal("example.com/pkg"))
+	})
+
+	o.Spec("ranks flaky packages", func(f fixture) {
+		w := f.get("/api/flaky/packages?repo=gocop")
+		f.expect(w.Body.String()).To(matchers.ContainSubstring(`"package":"example.com/flaky"`))
+		f.expect(f.store.filter.Limit).To(matchers.Equal(DefaultLimit + 1))
+	})
+
+	o.Spec("rejects invalid parameters", func(f fixture) {
+		f.expect(f.get("/api/runs?limit=0").Code).To(matchers.Equal(http.StatusBadRequest))
+		f.expect(f.get("/api/runs?limit=1000").Code).To(matchers.Equal(http.StatusBadRequest))
+		f.expect(f.get("/api/coverage?since=yesterday").Code).To(matchers.Equal(http.StatusBadRequest))
+	})
+
+	o.Spec("serves a valid OpenAPI description", func(f fixture) {
+		w := f.get("/api/openapi.json")
+		var doc map[string]interface{}
+		f.expect(json.Unmarshal(w.Body.Bytes(), &doc)).To(matchers.BeNil())
+		f.expect(doc["openapi"]).To(matchers.Equal("3.0.3"))
+	})
+
+	o.Spec("manages quarantine entries", func(f fixture) {
+		w := f.do(http.MethodPost, "/api/quarantine", `{"repo": "gocop", "package": "example.com/flaky", "reason": "flaky"}`, "Authorization", "Bearer secret")
+		f.expect(w.Code).To(matchers.Equal(http.StatusCreated))
+		f.expect(f.store.quarantine).To(matchers.HaveLen(1))
+
+		f.expect(f.do(http.MethodPost, "/api/quarantine", `{"repo": "gocop"}`, "Authorization", "Bearer secret").Code).To(matchers.Equal(http.StatusBadRequest))
+		f.expect(f.get("/api/quarantine?repo=gocop").Body.String()).To(matchers.ContainSubstring(`"package":"example.com/flaky"`))
+
+		w = f.do(http.MethodDelete, "/api/quarantine?repo=gocop&package=example.com/flaky", "", "Authorization", "Bearer secret")
+		f.expect(w.Code).To(matchers.Equal(http.StatusNoContent))
+		f.expect(f.store.quarantine).To(matchers.HaveLen(0))
+		f.expect(f.do(http.MethodDelete, "/api/quarantine?repo=gocop&package=example.com/flaky", "", "Authorization", "Bearer secret").Code).To(matchers.Equal(http.StatusNotFound))
+		f.expect(f.do(http.MethodPut, "/api/quarantine", "").Code).To(matchers.Equal(http.StatusMethodNotAllowed))
+	})
+
+	o.Spec("serves the dashboard", func(f fixture) {
+		w := f.get("/")
+		f.expect(w.Code).To(matchers.Equal(http.StatusOK))
+		f.expect(w.Body.String()).To(matchers.ContainSubstring(`<script src="/static/app.js">`))
+
+		w = f.get("/static/app.js")
+		f.expect(w.Header().Get("Content-Type")).To(matchers.StartWith("application/javascript"))
+		f.expect(w.Body.String()).To(matchers.ContainSubstring(`'Authorization': 'Bearer ' + token`))
+		f.expect(f.get("/static/missing.js").Code).To(matchers.Equal(http.StatusNotFound))
+		f.expect(f.get("/missing").Code).To(matchers.Equal(http.StatusNotFound))
+	})
+
+	o.Spec("parses and stores uploaded output", func(f fixture) {
+		body := `{"run": {"repo": "gocop", "build_id": 7, "cmd": "go test -race -count=1 ./..."}, "output": "ok  \texample.com/pass\t0.1s\nFAIL\texample.com/fail\t0.2s\n", "retests": ["FAIL\texample.com/fail\t0.2s\n", "ok  \texample.com/fail\t0.2s\n"]}`
+		w := f.do(http.MethodPost, "/api/runs", body, "Authorization", "Bearer secret")
+		f.expect(w.Code).To(matchers.Equal(http.StatusCreated))
+		f.expect(f.store.stored).To(matchers.HaveLen(2))
+		f.expect(f.store.flaky).To(matchers.HaveLen(1))
+
+		stored := f.store.runs[len(f.store.runs)-1]
+		f.expect(stored.BuildID).To(matchers.Equal(int64(7)))
+		f.expect(stored.Created.IsZero()).To(matchers.BeFalse())
+		f.expect(stored.Flags.Count).To(matchers.Equal(1))
+		f.expect(stored.Flags.Race).To(matchers.BeTrue())
+	})
+
+	o.Spec("stores uploaded results", func(f fixture) {
+		body := `{"run": {"repo": "gocop"}, "results": [{"package": "example.com/pass", "outcome": "pass"}]}`
+		f.expect(f.do(http.MethodPost, "/api/runs", body, "Authorization", "Bearer secret").Code).To(matchers.Equal(http.StatusCreated))
+		f.expect(f.store.stored[0].Package).To(matchers.Equal("example.com/pass"))
+
+		f.expect(f.do(http.MethodPost, "/api/runs", `{"run": {}}`, "Authorization", "Bearer secret").Code).To(matchers.Equal(http.StatusBadRequest))
+	})
+
+	o.Spec("requires a token for uploads", func(f fixture) {
+		body := `{"run": {"repo": "gocop"}, "results": [{"package": "example.com/pass", "outcome": "pass"}]}`
+		f.expect(f.do(http.MethodPost, "/api/runs", body).Code).To(matchers.Equal(http.StatusUnauthorized))
+		f.expect(f.do(http.MethodPost, "/api/runs", body, "Authorization", "Bearer wrong").Code).To(matchers.Equal(http.StatusUnauthorized))
+
+		w := httptest.NewRecorder()
+		New(f.store, Config{}).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/runs", strings.NewReader(body)))
+		f.expect(w.Code).To(matchers.Equal(http.StatusForbidden))
+	})
+
+	o.Spec("requires a token for quarantine changes", func(f fixture) {
+		body := `{"repo": "gocop", "package": "example.com/flaky", "reason": "flaky"}`
+		f.expect(f.do(http.MethodPost, "/api/quarantine", body).Code).To(matchers.Equal(http.StatusUnauthorized))
+		f.expect(f.do(http.MethodPost, "/api/quarantine", body, "Authorization", "Bearer wrong").Code).To(matchers.Equal(http.StatusUnauthorized))
+		f.expect(f.do(http.MethodDelete, "/api/quarantine?repo=gocop&package=example.com/flaky", "").Code).To(matchers.Equal(http.StatusUnauthorized))
+		f.expect(f.store.quarantine).To(matchers.HaveLen(0))
+		f.expect(f.get("/api/quarantine?repo=gocop").Code).To(matchers.Equal(http.StatusOK))
+	})
+
+	o.Spec("exports test health metrics", func(f fixture) {
+		w := f.get("/metrics")
+		f.expect(w.Code).To(matchers.Equal(http.StatusOK))
+		f.expect(w.Header().Get("Content-Type")).To(matchers.StartWith("text/plain; version=0.0.4"))
+		f.expect(w.Body.String()).To(matchers.ContainSubstring(`gocop_runs{repo="gocop",branch="master"} 3`))
+		f.expect(w.Body.String()).To(matchers.ContainSubstring(`gocop_package_pass_rate{repo="gocop",branch="master",package="example.com/flaky"} 0.75`))
+		f.expect(w.Body.String()).To(matchers.ContainSubstring(`gocop_failing_packages{repo="gocop",branch="master"} 1`))
+		f.expect(w.Body.String()).To(matchers.ContainSubstring("# TYPE gocop_flaky_packages gauge"))
+	})
+}